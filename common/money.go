@@ -0,0 +1,145 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const DefaultCurrency = "IDR"
+
+// currencyExponents holds the number of minor units digits of every
+// supported ISO 4217 currency
+var currencyExponents = map[string]int{
+	"IDR": 2,
+}
+
+// Money is an amount in the minor units of its currency, so prices never
+// suffer from floating point rounding
+type Money struct {
+	Amount   int64  `gorm:"not null;default:0" json:"amount"`
+	Currency string `gorm:"type:varchar(3);not null;default:'IDR'" json:"currency"`
+}
+
+func IsSupportedCurrency(currency string) bool {
+	_, ok := currencyExponents[currency]
+	return ok
+}
+
+// NewMoney converts a major units amount, e.g. 50000.50 rupiah, into Money
+func NewMoney(major float64, currency string) Money {
+	return Money{Amount: int64(math.Round(major * minorPerMajor(currency))), Currency: currency}
+}
+
+func minorPerMajor(currency string) float64 {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		exponent = currencyExponents[DefaultCurrency]
+	}
+	return math.Pow10(exponent)
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// WithDefaultCurrency fills in the default currency of a Money without one,
+// such as a price left out of a request
+func (m Money) WithDefaultCurrency() Money {
+	return Money{Amount: m.Amount, Currency: m.currency()}
+}
+
+func (m Money) HasSupportedCurrency() bool {
+	return IsSupportedCurrency(m.currency())
+}
+
+// Major returns the amount in major units, for display only
+func (m Money) Major() float64 {
+	return float64(m.Amount) / minorPerMajor(m.currency())
+}
+
+func (m Money) String() string {
+	exponent := currencyExponents[m.currency()]
+	return m.currency() + " " + strconv.FormatFloat(m.Major(), 'f', exponent, 64)
+}
+
+func (m Money) SameCurrency(other Money) bool {
+	return m.currency() == other.currency()
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.currency()}
+}
+
+// Percent returns pct percent of m, rounded to the nearest minor unit
+func (m Money) Percent(pct float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * pct / 100)), Currency: m.currency()}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Amount, m.currency()})
+}
+
+// UnmarshalJSON accepts either {"amount": <minor units>, "currency": "IDR"}
+// or a bare number in major units of the default currency, which is how
+// prices were sent and stored before Money existed
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '{' {
+		major, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return errors.New("money must be an object or a number")
+		}
+		*m = NewMoney(major, DefaultCurrency)
+		return nil
+	}
+
+	var raw struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	m.Amount = raw.Amount
+	m.Currency = strings.ToUpper(raw.Currency)
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	return nil
+}
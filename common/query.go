@@ -0,0 +1,117 @@
+package common
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// QueryOptions lists what a list endpoint can be sorted and filtered by. Both
+// maps go from the query parameter name to the column it applies to, so only
+// whitelisted columns ever reach SQL.
+type QueryOptions struct {
+	SortFields   map[string]string
+	FilterFields map[string]string
+	DefaultSort  string
+}
+
+// Query is a parsed list request: a page, an order and equality filters
+type Query struct {
+	Page    int
+	Limit   int
+	Sort    string
+	Desc    bool
+	Filters map[string]string
+}
+
+type Meta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// ParseQuery reads page, limit, sort (a field, descending when prefixed
+// with "-") and filters from the query string of a list request
+func ParseQuery(values url.Values, options QueryOptions) (Query, error) {
+	query := Query{Page: 1, Limit: DefaultPageLimit, Filters: map[string]string{}}
+
+	if values.Get("page") != "" {
+		page, err := strconv.Atoi(values.Get("page"))
+		if err != nil || page < 1 {
+			return Query{}, errors.New("page must be a positive number")
+		}
+		query.Page = page
+	}
+
+	if values.Get("limit") != "" {
+		limit, err := strconv.Atoi(values.Get("limit"))
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			return Query{}, errors.New("limit must be between 1 and " + strconv.Itoa(MaxPageLimit))
+		}
+		query.Limit = limit
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = options.DefaultSort
+	}
+	if sort != "" {
+		query.Desc = strings.HasPrefix(sort, "-")
+		column, ok := options.SortFields[strings.TrimPrefix(sort, "-")]
+		if !ok {
+			return Query{}, errors.New("cannot sort by " + strings.TrimPrefix(sort, "-"))
+		}
+		query.Sort = column
+	}
+
+	for field, column := range options.FilterFields {
+		if values.Get(field) != "" {
+			query.Filters[column] = values.Get(field)
+		}
+	}
+
+	return query, nil
+}
+
+// Filter applies the filters of the query, use it for counting
+func (q Query) Filter(db *gorm.DB) *gorm.DB {
+	for column, value := range q.Filters {
+		db = db.Where(column+" = ?", value)
+	}
+	return db
+}
+
+// Paginate applies the filters, order and page of the query
+func (q Query) Paginate(db *gorm.DB) *gorm.DB {
+	db = q.Filter(db)
+	if q.Sort != "" {
+		order := q.Sort
+		if q.Desc {
+			order += " DESC"
+		}
+		db = db.Order(order)
+		if q.Sort != "id" {
+			// keeps pages stable when the sort column has ties
+			db = db.Order("id")
+		}
+	}
+	return db.Offset((q.Page - 1) * q.Limit).Limit(q.Limit)
+}
+
+func (q Query) Meta(total int64) Meta {
+	return Meta{
+		Page:       q.Page,
+		Limit:      q.Limit,
+		Total:      total,
+		TotalPages: int((total + int64(q.Limit) - 1) / int64(q.Limit)),
+	}
+}
@@ -5,11 +5,14 @@ type Response struct {
 	Message   string `json:"message"`
 	Status    uint   `json:"status"`
 	Data      any    `json:"data"`
+	Meta      *Meta  `json:"meta,omitempty"`
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
-	Role  string `json:"role"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	DeviceID     string `json:"device_id"`
+	Role         string `json:"role"`
 }
 
 type EmptyObj struct {
@@ -27,14 +30,20 @@ func CreateSuccessResponse(msg string, statusCode uint, d any) Response {
 	}
 }
 
+func CreatePaginatedResponse(msg string, statusCode uint, d any, meta Meta) Response {
+	return Response{
+		IsSuccess: true, Message: msg, Status: statusCode, Data: d, Meta: &meta,
+	}
+}
+
 func CreateEmptySuccessResponse(msg string, statusCode uint) Response {
 	return Response{
 		IsSuccess: true, Message: msg, Status: statusCode, Data: nil,
 	}
 }
 
-func CreateAuthResponse(token string, role string) AuthResponse {
+func CreateAuthResponse(token string, refreshToken string, deviceID string, role string) AuthResponse {
 	return AuthResponse{
-		Token: token, Role: role,
+		Token: token, RefreshToken: refreshToken, DeviceID: deviceID, Role: role,
 	}
 }
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	location     *time.Location
+	locationOnce sync.Once
+)
+
+// Location returns the timezone of the cinema, which sessions are scheduled
+// and shown in
+func Location() *time.Location {
+	locationOnce.Do(func() {
+		name := os.Getenv("APP_TIMEZONE")
+		if name == "" {
+			name = "Asia/Jakarta"
+		}
+
+		var err error
+		location, err = time.LoadLocation(name)
+		if err != nil {
+			location = time.FixedZone("WIB", 7*60*60)
+		}
+	})
+	return location
+}
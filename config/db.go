@@ -38,12 +38,29 @@ func DBSetup() *gorm.DB {
 		entity.Session{},
 		entity.Transaction{},
 		entity.Spot{},
+		entity.TransactionLine{},
+		entity.PriceRule{},
+		entity.PromoCode{},
+		entity.Genre{},
+		entity.Person{},
+		entity.FilmCredit{},
+		entity.RefreshToken{},
+		entity.RevokedToken{},
+		entity.Permission{},
+		entity.Role{},
+		entity.PasswordResetToken{},
 	)
 	if err != nil {
 		fmt.Println(err)
 		panic(err)
 	}
 
+	err = migrate(db)
+	if err != nil {
+		fmt.Println(err)
+		panic(err)
+	}
+
 	return db
 }
 
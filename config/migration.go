@@ -0,0 +1,301 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/common"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"fp-rpl/utils"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrate runs the data migrations AutoMigrate can't express. Every step
+// runs on each start-up, so each one must be idempotent.
+func migrate(db *gorm.DB) error {
+	// Transactions made before payment tracking existed were settled on the spot
+	err := db.Exec("UPDATE transactions SET status = $1 WHERE status IS NULL OR status = ''", entity.TransactionPaid).Error
+	if err != nil {
+		return err
+	}
+
+	// Spots made before seat types existed are regular seats
+	err = db.Exec("UPDATE spots SET type = $1 WHERE type IS NULL OR type = ''", entity.SpotRegular).Error
+	if err != nil {
+		return err
+	}
+
+	err = migrateSessionTimes(db)
+	if err != nil {
+		return err
+	}
+
+	// Sessions made before end times were stored end with their film
+	err = db.Exec("UPDATE sessions SET ends_at = sessions.starts_at + make_interval(mins => films.duration) FROM films WHERE films.id = sessions.film_id AND sessions.ends_at IS NULL").Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Exec("UPDATE sessions SET ends_at = starts_at WHERE ends_at IS NULL").Error
+	if err != nil {
+		return err
+	}
+
+	// Prices used to be float columns in major units, move them into the
+	// Money columns. Price adjustments stored as JSON keep their old numbers,
+	// which common.Money still decodes as major units.
+	moneyColumns := []struct {
+		model  any
+		table  string
+		column string
+	}{
+		{entity.Session{}, "sessions", "price"},
+		{entity.Area{}, "areas", "base_price"},
+		{entity.PriceRule{}, "price_rules", "amount"},
+		{entity.PromoCode{}, "promo_codes", "amount"},
+		{entity.Transaction{}, "transactions", "subtotal_price"},
+		{entity.Transaction{}, "transactions", "discount_price"},
+		{entity.Transaction{}, "transactions", "total_price"},
+		{entity.TransactionLine{}, "transaction_lines", "base_price"},
+		{entity.TransactionLine{}, "transaction_lines", "price"},
+	}
+	for _, money := range moneyColumns {
+		err = migrateMoneyColumn(db, money.model, money.table, money.column)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = migrateFilmCredits(db)
+	if err != nil {
+		return err
+	}
+
+	err = migrateFilmSearch(db)
+	if err != nil {
+		return err
+	}
+
+	return seedRoles(db)
+}
+
+// seedRoles creates the permissions the code checks and the default roles.
+// The admin role is given every permission on each start-up, the others only
+// when they are created so changes made to them afterwards are kept.
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var permissions []entity.Permission
+		for _, seed := range entity.Permissions {
+			permission := entity.Permission{}
+			err := tx.Assign(entity.Permission{Description: seed.Description}).FirstOrCreate(&permission, entity.Permission{Name: seed.Name}).Error
+			if err != nil {
+				return err
+			}
+			permissions = append(permissions, permission)
+		}
+
+		admin := entity.Role{Name: entity.RoleAdmin, Description: "Has every permission"}
+		err := tx.Where("name = ?", admin.Name).Omit("Permissions").FirstOrCreate(&admin).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&admin).Omit("Permissions.*").Association("Permissions").Replace(permissions)
+		if err != nil {
+			return err
+		}
+
+		for name, names := range entity.DefaultRoles {
+			err = tx.Where("name = ?", name).Take(&entity.Role{}).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			role := entity.Role{Name: name}
+			for _, permission := range permissions {
+				for _, permissionName := range names {
+					if permission.Name == permissionName {
+						role.Permissions = append(role.Permissions, permission)
+					}
+				}
+			}
+			err = tx.Omit("Permissions.*").Create(&role).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Users made before roles existed are regular users
+		return tx.Exec("UPDATE users SET role = ? WHERE role IS NULL OR role = ''", entity.RoleUser).Error
+	})
+}
+
+// migrateFilmCredits moves the comma separated genre, director, writer,
+// producer and cast of films into genres, people and film credits. Names
+// with the same slug are taken to be the same genre or person.
+func migrateFilmCredits(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&entity.Film{}, "genre") {
+		return nil
+	}
+
+	var films []struct {
+		ID       uint64
+		Genre    string
+		Director string
+		Writer   string
+		Producer string
+		Cast     string
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table("films").Select(`id, genre, director, writer, producer, "cast"`).Scan(&films).Error
+		if err != nil {
+			return err
+		}
+
+		for _, film := range films {
+			for _, name := range splitNames(film.Genre) {
+				genre := entity.Genre{Name: name, Slug: utils.Slugify(name)}
+				err = tx.Where("slug = ?", genre.Slug).FirstOrCreate(&genre).Error
+				if err != nil {
+					return err
+				}
+
+				err = tx.Exec("INSERT INTO film_genres (film_id, genre_id) VALUES (?, ?) ON CONFLICT DO NOTHING", film.ID, genre.ID).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			credits := []struct {
+				role  string
+				names string
+			}{
+				{entity.CreditDirector, film.Director},
+				{entity.CreditWriter, film.Writer},
+				{entity.CreditProducer, film.Producer},
+				{entity.CreditCast, film.Cast},
+			}
+			for _, credit := range credits {
+				for i, name := range splitNames(credit.names) {
+					person := entity.Person{Name: name, Slug: utils.Slugify(name)}
+					err = tx.Where("slug = ?", person.Slug).FirstOrCreate(&person).Error
+					if err != nil {
+						return err
+					}
+
+					filmCredit := entity.FilmCredit{FilmID: film.ID, PersonID: person.ID, Role: credit.role, Billing: i + 1}
+					err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&filmCredit).Error
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		// The old search vector was generated from these columns
+		err = tx.Exec("ALTER TABLE films DROP COLUMN IF EXISTS search_vector").Error
+		if err != nil {
+			return err
+		}
+
+		for _, column := range []string{"genre", "director", "writer", "producer", "cast"} {
+			err = tx.Migrator().DropColumn(&entity.Film{}, column)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// splitNames splits a comma separated list of names, leaving out blank ones
+func splitNames(names string) []string {
+	var split []string
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if utils.Slugify(name) != "" {
+			split = append(split, name)
+		}
+	}
+	return split
+}
+
+// migrateFilmSearch adds the weighted full-text search vector of films and
+// the indexes film search relies on, including the trigram ones used to
+// still find films when the search has a typo. The vector is kept up to date
+// by the film repository, films saved before it existed get theirs here.
+func migrateFilmSearch(db *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"ALTER TABLE films ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		"CREATE INDEX IF NOT EXISTS idx_films_search_vector ON films USING GIN (search_vector)",
+		"CREATE INDEX IF NOT EXISTS idx_films_title_trgm ON films USING GIN (title gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_people_name_trgm ON people USING GIN (name gin_trgm_ops)",
+	}
+	for _, statement := range statements {
+		err := db.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	var filmIDs []uint64
+	err := db.Model(&entity.Film{}).Where("search_vector IS NULL").Pluck("id", &filmIDs).Error
+	if err != nil {
+		return err
+	}
+	return repository.NewFilmRepository(db).RefreshFilmSearch(context.Background(), nil, filmIDs)
+}
+
+// migrateSessionTimes replaces the time of day sessions used to be stored
+// with by real timestamps. The date was never kept, so each session is put on
+// the day it was created in the cinema timezone, or the day after when that
+// time had already passed, as sessions can't be created in the past.
+func migrateSessionTimes(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&entity.Session{}, "time") {
+		return nil
+	}
+
+	timezone := common.Location().String()
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE sessions SET starts_at = ((created_at AT TIME ZONE ?)::date + time) AT TIME ZONE ? WHERE time IS NOT NULL", timezone, timezone).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE sessions SET starts_at = starts_at + INTERVAL '1 day' WHERE starts_at < created_at").Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE sessions SET starts_at = created_at WHERE starts_at IS NULL").Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Migrator().DropColumn(&entity.Session{}, "time")
+	})
+}
+
+// migrateMoneyColumn converts the float column of a table into the
+// <column>_amount and <column>_currency columns of an embedded common.Money
+func migrateMoneyColumn(db *gorm.DB, model any, table string, column string) error {
+	if !db.Migrator().HasColumn(model, column) {
+		return nil
+	}
+
+	minor := common.NewMoney(1, common.DefaultCurrency).Amount
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE "+table+" SET "+column+"_amount = ROUND("+column+" * ?), "+column+"_currency = ? WHERE "+column+" IS NOT NULL", minor, common.DefaultCurrency).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(model, column)
+	})
+}
@@ -22,6 +22,7 @@ type AreaController interface {
 	GetAreaByID(ctx *gin.Context)
 	UpdateAreaByID(ctx *gin.Context)
 	DeleteAreaByID(ctx *gin.Context)
+	UpdateAreaLayoutByID(ctx *gin.Context)
 }
 
 func NewAreaController(areaS service.AreaService) AreaController {
@@ -37,7 +38,7 @@ func (areaC *areaController) CreateArea(ctx *gin.Context) {
 		return
 	}
 
-	if areaDTO.SpotCount <= 0 || areaDTO.SpotPerRow <= 0 {
+	if areaDTO.SpotCount <= 0 || areaDTO.SpotPerRow <= 0 || areaDTO.BasePrice.IsNegative() || !areaDTO.BasePrice.HasSupportedCurrency() || areaDTO.TurnaroundMinutes < 0 {
 		resp := common.CreateFailResponse("entered value invalid", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
@@ -73,8 +74,20 @@ func (areaC *areaController) CreateArea(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+var areaQueryOptions = common.QueryOptions{
+	SortFields:  map[string]string{"id": "id", "name": "name", "created_at": "created_at"},
+	DefaultSort: "id",
+}
+
 func (areaC *areaController) GetAllAreas(ctx *gin.Context) {
-	areas, err := areaC.areaService.GetAllAreas(ctx)
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), areaQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	areas, meta, err := areaC.areaService.GetAllAreas(ctx, query)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to fetch all areas", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -83,9 +96,9 @@ func (areaC *areaController) GetAllAreas(ctx *gin.Context) {
 
 	var resp common.Response
 	if len(areas) == 0 {
-		resp = common.CreateSuccessResponse("no area found", http.StatusOK, areas)
+		resp = common.CreatePaginatedResponse("no area found", http.StatusOK, areas, meta)
 	} else {
-		resp = common.CreateSuccessResponse("successfully fetched all areas", http.StatusOK, areas)
+		resp = common.CreatePaginatedResponse("successfully fetched all areas", http.StatusOK, areas, meta)
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -130,7 +143,7 @@ func (areaC *areaController) UpdateAreaByID(ctx *gin.Context) {
 		return
 	}
 
-	if areaDTO.SpotCount <= 0 || areaDTO.SpotPerRow <= 0 {
+	if areaDTO.SpotCount <= 0 || areaDTO.SpotPerRow <= 0 || areaDTO.BasePrice.IsNegative() || !areaDTO.BasePrice.HasSupportedCurrency() || areaDTO.TurnaroundMinutes < 0 {
 		resp := common.CreateFailResponse("entered value invalid", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
@@ -186,6 +199,46 @@ func (areaC *areaController) UpdateAreaByID(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
+func (areaC *areaController) UpdateAreaLayoutByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update area layout request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var areaDTO dto.AreaLayoutRequest
+	err = ctx.ShouldBind(&areaDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process area layout update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	area, err := areaC.areaService.GetAreaByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process area layout update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(area, entity.Area{}) {
+		resp := common.CreateFailResponse("area with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	area, err = areaC.areaService.UpdateAreaLayout(ctx, areaDTO.Layout, area)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated area layout", http.StatusOK, area)
+	ctx.JSON(http.StatusOK, resp)
+}
+
 func (areaC *areaController) DeleteAreaByID(ctx *gin.Context) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
@@ -5,8 +5,10 @@ import (
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/service"
+	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,11 +25,87 @@ type FilmController interface {
 	DeleteFilm(ctx *gin.Context)
 	GetAllFilmsNowPlaying(ctx *gin.Context)
 	GetAllFilmsComingSoon(ctx *gin.Context)
+	SearchFilms(ctx *gin.Context)
+	UploadFilmPoster(ctx *gin.Context)
+	DeleteFilmPoster(ctx *gin.Context)
+	UploadFilmTrailer(ctx *gin.Context)
+	DeleteFilmTrailer(ctx *gin.Context)
 }
 
 func NewFilmController(filmS service.FilmService) FilmController {
 	return &filmController{filmService: filmS}
 }
+
+// validateFilmSchedule checks the release and end dates of a film request,
+// and that it has a status when the status isn't set from them
+func validateFilmSchedule(filmDTO dto.FilmRegisterRequest) string {
+	releaseDate, err := service.ParseFilmDate(filmDTO.ReleaseDate)
+	if err != nil {
+		return "release " + err.Error()
+	}
+
+	endDate, err := service.ParseFilmDate(filmDTO.EndDate)
+	if err != nil {
+		return "end " + err.Error()
+	}
+
+	if endDate != nil && releaseDate == nil {
+		return "end date requires a release date"
+	}
+
+	if endDate != nil && endDate.Before(*releaseDate) {
+		return "end date must not be before release date"
+	}
+
+	if (releaseDate == nil || filmDTO.StatusOverride) && filmDTO.StatusCode.String() == "Unknown" {
+		return "status is invalid"
+	}
+	return ""
+}
+
+// validateFilmRelations checks the genres and credits of a film request
+// before they are looked up
+func validateFilmRelations(filmDTO dto.FilmRegisterRequest) string {
+	genres := map[uint64]bool{}
+	for _, id := range filmDTO.GenreIDs {
+		if genres[id] {
+			return "genre with id " + strconv.FormatUint(id, 10) + " is listed twice"
+		}
+		genres[id] = true
+	}
+
+	type credit struct {
+		personID uint64
+		role     string
+	}
+	credits := map[credit]bool{}
+	for _, c := range filmDTO.Credits {
+		if !entity.IsCreditRole(c.Role) {
+			return "credit role " + c.Role + " is invalid"
+		}
+		if credits[credit{c.PersonID, c.Role}] {
+			return "person with id " + strconv.FormatUint(c.PersonID, 10) + " is credited twice as " + c.Role
+		}
+		credits[credit{c.PersonID, c.Role}] = true
+	}
+	return ""
+}
+
+func (fc *filmController) checkFilmRelations(ctx *gin.Context, filmDTO dto.FilmRegisterRequest) bool {
+	if msg := validateFilmRelations(filmDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return false
+	}
+
+	err := fc.filmService.CheckFilmRelations(ctx, filmDTO)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return false
+	}
+	return true
+}
 func (fc *filmController) CreateFilm(ctx *gin.Context) {
 	var filmDTO dto.FilmRegisterRequest
 	err := ctx.ShouldBind(&filmDTO)
@@ -49,12 +127,25 @@ func (fc *filmController) CreateFilm(ctx *gin.Context) {
 	}
 
 	filmDTO.Status = filmDTO.StatusCode.String()
-	if filmDTO.Status == "Unknown" {
-		resp := common.CreateFailResponse("status is invalid", http.StatusBadRequest)
+	if msg := validateFilmSchedule(filmDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
+	if filmDTO.AgeRating == "" {
+		filmDTO.AgeRating = entity.RatingSU
+	}
+	if !entity.IsAgeRating(filmDTO.AgeRating) {
+		resp := common.CreateFailResponse("age rating must be one of SU, 13+, 17+ or 21+", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !fc.checkFilmRelations(ctx, filmDTO) {
+		return
+	}
+
 	_, err = fc.filmService.CreateNewFilm(ctx, filmDTO)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to process create film request", http.StatusBadRequest)
@@ -64,14 +155,43 @@ func (fc *filmController) CreateFilm(ctx *gin.Context) {
 	resp := common.CreateEmptySuccessResponse("film succesfully created", http.StatusCreated)
 	ctx.JSON(http.StatusCreated, resp)
 }
+var filmQueryOptions = common.QueryOptions{
+	SortFields:   map[string]string{"id": "id", "title": "title", "duration": "duration", "created_at": "created_at"},
+	FilterFields: map[string]string{"status": "status"},
+	DefaultSort:  "id",
+}
+
 func (fc *filmController) GetAllFilms(ctx *gin.Context) {
-	films, err := fc.filmService.GetAllFilm(ctx)
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), filmQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	filter := dto.FilmFilter{Genre: ctx.Query("genre"), Role: ctx.Query("role")}
+	if ctx.Query("person") != "" {
+		filter.PersonID, err = strconv.ParseUint(ctx.Query("person"), 10, 64)
+		if err != nil {
+			resp := common.CreateFailResponse("person must be the id of a person", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	if filter.Role != "" && (filter.PersonID == 0 || !entity.IsCreditRole(filter.Role)) {
+		resp := common.CreateFailResponse("role must be a valid credit role and used together with person", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	films, meta, err := fc.filmService.GetAllFilm(ctx, query, filter)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to get all film", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
-	resp := common.CreateSuccessResponse("get film success", http.StatusOK, films)
+	resp := common.CreatePaginatedResponse("get film success", http.StatusOK, films, meta)
 	ctx.JSON(http.StatusOK, resp)
 }
 func (fc *filmController) GetAllFilmsNowPlaying(ctx *gin.Context) {
@@ -94,6 +214,29 @@ func (fc *filmController) GetAllFilmsComingSoon(ctx *gin.Context) {
 	resp := common.CreateSuccessResponse("get film success", http.StatusOK, films)
 	ctx.JSON(http.StatusOK, resp)
 }
+func (fc *filmController) SearchFilms(ctx *gin.Context) {
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), common.QueryOptions{})
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	films, meta, err := fc.filmService.SearchFilms(ctx, ctx.Query("q"), query)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(films) == 0 {
+		resp = common.CreatePaginatedResponse("no film found", http.StatusOK, films, meta)
+	} else {
+		resp = common.CreatePaginatedResponse("search film success", http.StatusOK, films, meta)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
 func (fc *filmController) GetFilmDetailBySlug(ctx *gin.Context) {
 	slug := ctx.Param("slug")
 	film, err := fc.filmService.GetFilmDetailBySlug(ctx, slug)
@@ -155,12 +298,25 @@ func (fc *filmController) UpdateFilm(ctx *gin.Context) {
 	}
 
 	filmDTO.Status = filmDTO.StatusCode.String()
-	if filmDTO.Status == "Unknown" {
-		resp := common.CreateFailResponse("status is invalid", http.StatusBadRequest)
+	if msg := validateFilmSchedule(filmDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
+	if filmDTO.AgeRating == "" {
+		filmDTO.AgeRating = entity.RatingSU
+	}
+	if !entity.IsAgeRating(filmDTO.AgeRating) {
+		resp := common.CreateFailResponse("age rating must be one of SU, 13+, 17+ or 21+", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !fc.checkFilmRelations(ctx, filmDTO) {
+		return
+	}
+
 	updatedFilm, err := fc.filmService.UpdateFilm(ctx,filmDTO,film)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to update film", http.StatusBadRequest)
@@ -196,3 +352,164 @@ func (fc *filmController) DeleteFilm(ctx *gin.Context) {
 	resp := common.CreateSuccessResponse("delete film success", http.StatusOK, nil)
 	ctx.JSON(http.StatusOK, resp)
 }
+
+func (fc *filmController) UploadFilmPoster(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	// Leaves room for the multipart headers around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.MaxPosterSize+1<<20)
+	fileHeader, err := ctx.FormFile("poster")
+	if err != nil {
+		resp := common.CreateFailResponse("poster must be uploaded as a multipart file of at most 5 MB", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if fileHeader.Size > service.MaxPosterSize {
+		resp := common.CreateFailResponse("poster must be at most 5 MB", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process poster upload request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxPosterSize))
+	file.Close()
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process poster upload request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err := fc.filmService.GetFilmBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process poster upload request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film with given slug not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err = fc.filmService.UploadFilmPoster(ctx, film, data)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("poster successfully uploaded", http.StatusOK, film)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (fc *filmController) DeleteFilmPoster(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	film, err := fc.filmService.GetFilmBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process poster delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film with given slug not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err = fc.filmService.DeleteFilmPoster(ctx, film)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("poster successfully deleted", http.StatusOK, film)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (fc *filmController) UploadFilmTrailer(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	// Leaves room for the multipart headers around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.MaxTrailerSize+1<<20)
+	fileHeader, err := ctx.FormFile("trailer")
+	if err != nil {
+		resp := common.CreateFailResponse("trailer must be uploaded as a multipart file of at most 200 MB", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if fileHeader.Size > service.MaxTrailerSize {
+		resp := common.CreateFailResponse("trailer must be at most 200 MB", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err := fc.filmService.GetFilmBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process trailer upload request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film with given slug not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// The file is streamed to the storage rather than read into memory
+	file, err := fileHeader.Open()
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process trailer upload request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+	defer file.Close()
+
+	film, err = fc.filmService.UploadFilmTrailer(ctx, film, file)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("trailer successfully uploaded", http.StatusOK, film)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (fc *filmController) DeleteFilmTrailer(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	film, err := fc.filmService.GetFilmBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process trailer delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film with given slug not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err = fc.filmService.DeleteFilmTrailer(ctx, film)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("trailer successfully deleted", http.StatusOK, film)
+	ctx.JSON(http.StatusOK, resp)
+}
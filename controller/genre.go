@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/service"
+	"fp-rpl/utils"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type genreController struct {
+	genreService service.GenreService
+}
+
+type GenreController interface {
+	CreateGenre(ctx *gin.Context)
+	GetAllGenres(ctx *gin.Context)
+	UpdateGenreByID(ctx *gin.Context)
+	DeleteGenreByID(ctx *gin.Context)
+}
+
+func NewGenreController(genreS service.GenreService) GenreController {
+	return &genreController{genreService: genreS}
+}
+
+func (genreC *genreController) CreateGenre(ctx *gin.Context) {
+	var genreDTO dto.GenreCreateRequest
+	err := ctx.ShouldBind(&genreDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if utils.Slugify(genreDTO.Name) == "" {
+		resp := common.CreateFailResponse("name must contain a letter or a digit", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	genre, err := genreC.genreService.GetGenreBySlug(ctx, utils.Slugify(genreDTO.Name))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(genre, entity.Genre{})) {
+		resp := common.CreateFailResponse("genre already exists", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	genre, err = genreC.genreService.CreateNewGenre(ctx, genreDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created genre", http.StatusCreated, genre)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (genreC *genreController) GetAllGenres(ctx *gin.Context) {
+	genres, err := genreC.genreService.GetAllGenres(ctx)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all genres", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(genres) == 0 {
+		resp = common.CreateSuccessResponse("no genre found", http.StatusOK, genres)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched all genres", http.StatusOK, genres)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (genreC *genreController) UpdateGenreByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update genre request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var genreDTO dto.GenreCreateRequest
+	err = ctx.ShouldBind(&genreDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if utils.Slugify(genreDTO.Name) == "" {
+		resp := common.CreateFailResponse("name must contain a letter or a digit", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	genre, err := genreC.genreService.GetGenreByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(genre, entity.Genre{}) {
+		resp := common.CreateFailResponse("genre with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	sameGenre, err := genreC.genreService.GetGenreBySlug(ctx, utils.Slugify(genreDTO.Name))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(sameGenre, entity.Genre{})) && sameGenre.ID != genre.ID {
+		resp := common.CreateFailResponse("genre already exists", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	genre, err = genreC.genreService.UpdateGenre(ctx, genreDTO, genre)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated genre", http.StatusOK, genre)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (genreC *genreController) DeleteGenreByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of delete genre request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	genre, err := genreC.genreService.GetGenreByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(genre, entity.Genre{}) {
+		resp := common.CreateFailResponse("genre with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	err = genreC.genreService.DeleteGenreByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process genre delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully deleted genre", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"errors"
+	"fp-rpl/common"
+	"fp-rpl/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mediaController struct {
+	storage service.Storage
+}
+
+type MediaController interface {
+	GetMedia(ctx *gin.Context)
+}
+
+func NewMediaController(storage service.Storage) MediaController {
+	return &mediaController{storage: storage}
+}
+
+func (mc *mediaController) GetMedia(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	// Files are never changed under a key, a new upload gets a new key, so
+	// they can be cached for good
+	etag := `"` + key + `"`
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Header("ETag", etag)
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := mc.storage.Get(ctx, key)
+	if errors.Is(err, service.ErrMediaNotFound) {
+		ctx.Header("Cache-Control", "no-store")
+		resp := common.CreateFailResponse("media not found", http.StatusNotFound)
+		ctx.AbortWithStatusJSON(http.StatusNotFound, resp)
+		return
+	}
+	if err != nil {
+		ctx.Header("Cache-Control", "no-store")
+		resp := common.CreateFailResponse("failed to fetch media", http.StatusInternalServerError)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, resp)
+		return
+	}
+	defer body.Close()
+
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.DataFromReader(http.StatusOK, -1, service.MediaContentType(key), body, nil)
+}
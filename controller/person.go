@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/service"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type personController struct {
+	personService service.PersonService
+}
+
+type PersonController interface {
+	CreatePerson(ctx *gin.Context)
+	GetAllPeople(ctx *gin.Context)
+	GetPersonByID(ctx *gin.Context)
+	UpdatePersonByID(ctx *gin.Context)
+	DeletePersonByID(ctx *gin.Context)
+}
+
+func NewPersonController(personS service.PersonService) PersonController {
+	return &personController{personService: personS}
+}
+
+var personQueryOptions = common.QueryOptions{
+	SortFields:  map[string]string{"id": "id", "name": "name", "created_at": "created_at"},
+	DefaultSort: "name",
+}
+
+func (personC *personController) CreatePerson(ctx *gin.Context) {
+	var personDTO dto.PersonCreateRequest
+	err := ctx.ShouldBind(&personDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	slug := service.PersonSlug(personDTO)
+	if slug == "" {
+		resp := common.CreateFailResponse("name must contain a letter or a digit", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err := personC.personService.GetPersonBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(person, entity.Person{})) {
+		resp := common.CreateFailResponse("slug is already used", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err = personC.personService.CreateNewPerson(ctx, personDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created person", http.StatusCreated, person)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (personC *personController) GetAllPeople(ctx *gin.Context) {
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), personQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	people, meta, err := personC.personService.GetAllPeople(ctx, query)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all people", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(people) == 0 {
+		resp = common.CreatePaginatedResponse("no person found", http.StatusOK, people, meta)
+	} else {
+		resp = common.CreatePaginatedResponse("successfully fetched all people", http.StatusOK, people, meta)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (personC *personController) GetPersonByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of get person request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err := personC.personService.GetPersonDetailByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch person", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if reflect.DeepEqual(person, entity.Person{}) {
+		resp = common.CreateSuccessResponse("person not found", http.StatusOK, nil)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched person", http.StatusOK, person)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (personC *personController) UpdatePersonByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update person request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var personDTO dto.PersonCreateRequest
+	err = ctx.ShouldBind(&personDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	slug := service.PersonSlug(personDTO)
+	if slug == "" {
+		resp := common.CreateFailResponse("name must contain a letter or a digit", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err := personC.personService.GetPersonByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(person, entity.Person{}) {
+		resp := common.CreateFailResponse("person with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	samePerson, err := personC.personService.GetPersonBySlug(ctx, slug)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(samePerson, entity.Person{})) && samePerson.ID != person.ID {
+		resp := common.CreateFailResponse("slug is already used", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err = personC.personService.UpdatePerson(ctx, personDTO, person)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated person", http.StatusOK, person)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (personC *personController) DeletePersonByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of delete person request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	person, err := personC.personService.GetPersonByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(person, entity.Person{}) {
+		resp := common.CreateFailResponse("person with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	err = personC.personService.DeletePersonByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process person delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully deleted person", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/service"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type priceRuleController struct {
+	priceRuleService service.PriceRuleService
+}
+
+type PriceRuleController interface {
+	CreatePriceRule(ctx *gin.Context)
+	GetAllPriceRules(ctx *gin.Context)
+	GetPriceRuleByID(ctx *gin.Context)
+	UpdatePriceRuleByID(ctx *gin.Context)
+	DeletePriceRuleByID(ctx *gin.Context)
+}
+
+func NewPriceRuleController(priceRuleS service.PriceRuleService) PriceRuleController {
+	return &priceRuleController{priceRuleService: priceRuleS}
+}
+
+func validatePriceRule(priceRuleDTO dto.PriceRuleCreateRequest) string {
+	switch priceRuleDTO.SpotType {
+	case "", entity.SpotRegular, entity.SpotVIP, entity.SpotCouple, entity.SpotWheelchair:
+	default:
+		return "spot type is invalid"
+	}
+
+	switch priceRuleDTO.DayType {
+	case "", entity.DayWeekday, entity.DayWeekend:
+	default:
+		return "day type is invalid"
+	}
+
+	if !priceRuleDTO.Amount.HasSupportedCurrency() {
+		return "currency is not supported"
+	}
+
+	if (priceRuleDTO.StartTime == "") != (priceRuleDTO.EndTime == "") {
+		return "start time and end time must be given together"
+	}
+
+	if priceRuleDTO.StartTime != "" {
+		_, startErr := time.Parse("15:04", priceRuleDTO.StartTime)
+		_, endErr := time.Parse("15:04", priceRuleDTO.EndTime)
+		if startErr != nil || endErr != nil {
+			return "start time and end time must use the HH:MM format"
+		}
+	}
+
+	return ""
+}
+
+func (priceRuleC *priceRuleController) CreatePriceRule(ctx *gin.Context) {
+	var priceRuleDTO dto.PriceRuleCreateRequest
+	err := ctx.ShouldBind(&priceRuleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if msg := validatePriceRule(priceRuleDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	priceRule, err := priceRuleC.priceRuleService.CreateNewPriceRule(ctx, priceRuleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created price rule", http.StatusCreated, priceRule)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (priceRuleC *priceRuleController) GetAllPriceRules(ctx *gin.Context) {
+	priceRules, err := priceRuleC.priceRuleService.GetAllPriceRules(ctx)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all price rules", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(priceRules) == 0 {
+		resp = common.CreateSuccessResponse("no price rule found", http.StatusOK, priceRules)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched all price rules", http.StatusOK, priceRules)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (priceRuleC *priceRuleController) GetPriceRuleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of get price rule request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	priceRule, err := priceRuleC.priceRuleService.GetPriceRuleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch price rule", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if reflect.DeepEqual(priceRule, entity.PriceRule{}) {
+		resp = common.CreateSuccessResponse("price rule not found", http.StatusOK, nil)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched price rule", http.StatusOK, priceRule)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (priceRuleC *priceRuleController) UpdatePriceRuleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update price rule request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var priceRuleDTO dto.PriceRuleCreateRequest
+	err = ctx.ShouldBind(&priceRuleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if msg := validatePriceRule(priceRuleDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	priceRule, err := priceRuleC.priceRuleService.GetPriceRuleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(priceRule, entity.PriceRule{}) {
+		resp := common.CreateFailResponse("price rule with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	priceRule, err = priceRuleC.priceRuleService.UpdatePriceRule(ctx, priceRuleDTO, priceRule)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated price rule", http.StatusOK, priceRule)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (priceRuleC *priceRuleController) DeletePriceRuleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of delete price rule request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	priceRule, err := priceRuleC.priceRuleService.GetPriceRuleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(priceRule, entity.PriceRule{}) {
+		resp := common.CreateFailResponse("price rule with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	err = priceRuleC.priceRuleService.DeletePriceRuleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process price rule delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully deleted price rule", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
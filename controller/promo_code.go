@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/service"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type promoCodeController struct {
+	promoCodeService service.PromoCodeService
+}
+
+type PromoCodeController interface {
+	CreatePromoCode(ctx *gin.Context)
+	GetAllPromoCodes(ctx *gin.Context)
+	GetPromoCodeByID(ctx *gin.Context)
+	UpdatePromoCodeByID(ctx *gin.Context)
+	DeletePromoCodeByID(ctx *gin.Context)
+}
+
+func NewPromoCodeController(promoCodeS service.PromoCodeService) PromoCodeController {
+	return &promoCodeController{promoCodeService: promoCodeS}
+}
+
+func validatePromoCode(promoCodeDTO dto.PromoCodeCreateRequest) string {
+	if strings.TrimSpace(promoCodeDTO.Code) == "" {
+		return "code must not be empty"
+	}
+
+	switch promoCodeDTO.DiscountType {
+	case entity.PromoPercentage:
+		if promoCodeDTO.Percentage <= 0 || promoCodeDTO.Percentage > 100 {
+			return "percentage must be between 0 and 100"
+		}
+	case entity.PromoFixed:
+		if !promoCodeDTO.Amount.IsPositive() || !promoCodeDTO.Amount.HasSupportedCurrency() {
+			return "amount must be greater than 0"
+		}
+	default:
+		return "discount type is invalid"
+	}
+
+	if promoCodeDTO.ValidFrom != nil && promoCodeDTO.ValidUntil != nil && !promoCodeDTO.ValidFrom.Before(*promoCodeDTO.ValidUntil) {
+		return "valid from must be before valid until"
+	}
+
+	if promoCodeDTO.MinSpots < 0 || promoCodeDTO.MaxUses < 0 || promoCodeDTO.MaxUsesPerUser < 0 {
+		return "min spots and usage limits must not be negative"
+	}
+
+	return ""
+}
+
+func (promoCodeC *promoCodeController) CreatePromoCode(ctx *gin.Context) {
+	var promoCodeDTO dto.PromoCodeCreateRequest
+	err := ctx.ShouldBind(&promoCodeDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if msg := validatePromoCode(promoCodeDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err := promoCodeC.promoCodeService.GetPromoCodeByCode(ctx, promoCodeDTO.Code)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(promoCode, entity.PromoCode{})) {
+		resp := common.CreateFailResponse("code has already been used by another promo code", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err = promoCodeC.promoCodeService.CreateNewPromoCode(ctx, promoCodeDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created promo code", http.StatusCreated, promoCode)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (promoCodeC *promoCodeController) GetAllPromoCodes(ctx *gin.Context) {
+	promoCodes, err := promoCodeC.promoCodeService.GetAllPromoCodes(ctx)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all promo codes", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(promoCodes) == 0 {
+		resp = common.CreateSuccessResponse("no promo code found", http.StatusOK, promoCodes)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched all promo codes", http.StatusOK, promoCodes)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (promoCodeC *promoCodeController) GetPromoCodeByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of get promo code request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err := promoCodeC.promoCodeService.GetPromoCodeByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch promo code", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if reflect.DeepEqual(promoCode, entity.PromoCode{}) {
+		resp = common.CreateSuccessResponse("promo code not found", http.StatusOK, nil)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched promo code", http.StatusOK, promoCode)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (promoCodeC *promoCodeController) UpdatePromoCodeByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update promo code request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var promoCodeDTO dto.PromoCodeCreateRequest
+	err = ctx.ShouldBind(&promoCodeDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if msg := validatePromoCode(promoCodeDTO); msg != "" {
+		resp := common.CreateFailResponse(msg, http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err := promoCodeC.promoCodeService.GetPromoCodeByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(promoCode, entity.PromoCode{}) {
+		resp := common.CreateFailResponse("promo code with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	sameCode, err := promoCodeC.promoCodeService.GetPromoCodeByCode(ctx, promoCodeDTO.Code)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(sameCode, entity.PromoCode{})) && sameCode.ID != promoCode.ID {
+		resp := common.CreateFailResponse("code has already been used by another promo code", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err = promoCodeC.promoCodeService.UpdatePromoCode(ctx, promoCodeDTO, promoCode)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated promo code", http.StatusOK, promoCode)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (promoCodeC *promoCodeController) DeletePromoCodeByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of delete promo code request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	promoCode, err := promoCodeC.promoCodeService.GetPromoCodeByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(promoCode, entity.PromoCode{}) {
+		resp := common.CreateFailResponse("promo code with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	err = promoCodeC.promoCodeService.DeletePromoCodeByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process promo code delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully deleted promo code", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"errors"
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/service"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roleNamePattern keeps role names usable as they are in tokens and filters
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,63}$`)
+
+type roleController struct {
+	roleService service.RoleService
+	authService service.AuthService
+}
+
+type RoleController interface {
+	CreateRole(ctx *gin.Context)
+	GetAllRoles(ctx *gin.Context)
+	GetRoleByID(ctx *gin.Context)
+	UpdateRoleByID(ctx *gin.Context)
+	DeleteRoleByID(ctx *gin.Context)
+	GetAllPermissions(ctx *gin.Context)
+	UpdateUserRole(ctx *gin.Context)
+}
+
+func NewRoleController(roleS service.RoleService, authS service.AuthService) RoleController {
+	return &roleController{roleService: roleS, authService: authS}
+}
+
+func (roleC *roleController) CreateRole(ctx *gin.Context) {
+	var roleDTO dto.RoleCreateRequest
+	err := ctx.ShouldBind(&roleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process role create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !roleNamePattern.MatchString(roleDTO.Name) {
+		resp := common.CreateFailResponse("name must be 2 to 64 lower case letters, digits or underscores starting with a letter", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err := roleC.roleService.GetRoleByName(ctx, roleDTO.Name)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process role create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(role, entity.Role{})) {
+		resp := common.CreateFailResponse("role already exists", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err = roleC.roleService.CreateNewRole(ctx, roleDTO, editorClaims(ctx))
+	if errors.Is(err, service.ErrRolePermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created role", http.StatusCreated, role)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (roleC *roleController) GetAllRoles(ctx *gin.Context) {
+	roles, err := roleC.roleService.GetAllRoles(ctx)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all roles", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(roles) == 0 {
+		resp = common.CreateSuccessResponse("no role found", http.StatusOK, roles)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched all roles", http.StatusOK, roles)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (roleC *roleController) GetRoleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of get role request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err := roleC.roleService.GetRoleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch role", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if reflect.DeepEqual(role, entity.Role{}) {
+		resp = common.CreateSuccessResponse("role not found", http.StatusOK, nil)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched role", http.StatusOK, role)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (roleC *roleController) UpdateRoleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of update role request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var roleDTO dto.RoleUpdateRequest
+	err = ctx.ShouldBind(&roleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process role update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err := roleC.roleService.GetRoleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process role update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(role, entity.Role{}) {
+		resp := common.CreateFailResponse("role with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err = roleC.roleService.UpdateRole(ctx, roleDTO, role, editorClaims(ctx))
+	if errors.Is(err, service.ErrRolePermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated role", http.StatusOK, role)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (roleC *roleController) DeleteRoleByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of delete role request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	role, err := roleC.roleService.GetRoleByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process role delete request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(role, entity.Role{}) {
+		resp := common.CreateFailResponse("role with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	err = roleC.roleService.DeleteRole(ctx, role)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully deleted role", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (roleC *roleController) GetAllPermissions(ctx *gin.Context) {
+	permissions, err := roleC.roleService.GetAllPermissions(ctx)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch all permissions", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully fetched all permissions", http.StatusOK, permissions)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (roleC *roleController) UpdateUserRole(ctx *gin.Context) {
+	var roleDTO dto.UserRoleUpdateRequest
+	err := ctx.ShouldBind(&roleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user role update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err := roleC.roleService.AssignUserRole(ctx, roleDTO, ctx.Param("username"), editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateFailResponse("user with given username not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Access tokens carry the permissions of the role they were issued with
+	err = roleC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, "")
+	if err != nil {
+		resp := common.CreateFailResponse("user role was updated but failed to log them out", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated user role", http.StatusOK, user)
+	ctx.JSON(http.StatusOK, resp)
+}
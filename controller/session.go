@@ -5,6 +5,7 @@ import (
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/service"
+	"io"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -14,24 +15,35 @@ import (
 )
 
 type sessionController struct {
-	sessionService service.SessionService
-	areaService    service.AreaService
-	filmService    service.FilmService
+	sessionService   service.SessionService
+	areaService      service.AreaService
+	filmService      service.FilmService
+	spotService      service.SpotService
+	seatEventService service.SeatEventService
 }
 
 type SessionController interface {
 	CreateSession(ctx *gin.Context)
+	ScheduleSessions(ctx *gin.Context)
+	CheckSessionConflicts(ctx *gin.Context)
+	GetShowtimes(ctx *gin.Context)
 	GetAllSessions(ctx *gin.Context)
 	GetSessionsByFilmSlug(ctx *gin.Context)
 	DeleteSessionByID(ctx *gin.Context)
 	GetSessionDetailByID(ctx *gin.Context)
+	GetSessionSeatMap(ctx *gin.Context)
+	StreamSeatEvents(ctx *gin.Context)
+	HoldSpots(ctx *gin.Context)
+	ReleaseSpotHolds(ctx *gin.Context)
 }
 
-func NewSessionController(sessionS service.SessionService, areaS service.AreaService, filmS service.FilmService) SessionController {
+func NewSessionController(sessionS service.SessionService, areaS service.AreaService, filmS service.FilmService, spotS service.SpotService, seatEventS service.SeatEventService) SessionController {
 	return &sessionController{
-		sessionService: sessionS,
-		areaService:    areaS,
-		filmService:    filmS,
+		sessionService:   sessionS,
+		areaService:      areaS,
+		filmService:      filmS,
+		spotService:      spotS,
+		seatEventService: seatEventS,
 	}
 }
 
@@ -57,23 +69,69 @@ func (sessionC *sessionController) CreateSession(ctx *gin.Context) {
 		return
 	}
 
-	// Check for duplicate Session
-	sessionCheck, err := sessionC.sessionService.GetSessionByTimeAndPlace(ctx, sessionDTO)
+	// Check Film by ID
+	film, err := sessionC.filmService.GetFilmByID(ctx, sessionDTO.FilmID)
 	if err != nil {
-		resp := common.CreateFailResponse("failed to process session create request", http.StatusBadRequest)
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
-	// Check if duplicate is found
-	if !(reflect.DeepEqual(sessionCheck, entity.Session{})) {
-		resp := common.CreateFailResponse("session with the exact same attributes already exists", http.StatusBadRequest)
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !film.IsPlayingAt(checkTime) {
+		resp := common.CreateFailResponse("Film is not playing at the session time", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+	// Check Area by ID
+	area, err := sessionC.areaService.GetAreaByID(ctx, sessionDTO.AreaID)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(area, entity.Area{}) {
+		resp := common.CreateFailResponse("area not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Sessions without their own price sell at the area base price
+	if sessionDTO.Price.IsNegative() || !sessionDTO.Price.HasSupportedCurrency() || (sessionDTO.Price.IsZero() && !area.BasePrice.IsPositive()) {
+		resp := common.CreateFailResponse("session price invalid", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Overlapping sessions in the same area are rejected by the service
+	_, err = sessionC.sessionService.CreateNewSession(ctx, sessionDTO, film, area)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateEmptySuccessResponse("successfully created session", http.StatusCreated)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (sessionC *sessionController) ScheduleSessions(ctx *gin.Context) {
+	var scheduleDTO dto.SessionScheduleRequest
+	err := ctx.ShouldBind(&scheduleDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process session schedule request", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
 	// Check Film by ID
-	film, err := sessionC.filmService.GetFilmByID(ctx, sessionDTO.FilmID)
+	film, err := sessionC.filmService.GetFilmByID(ctx, scheduleDTO.FilmID)
 	if err != nil {
 		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -86,13 +144,16 @@ func (sessionC *sessionController) CreateSession(ctx *gin.Context) {
 		return
 	}
 
-	if film.Status != "Now Playing" {
+	// Films with a release date can be scheduled ahead of it, the slots
+	// outside of their run are skipped
+	if !film.FollowsSchedule() && film.Status != entity.FilmNowPlaying {
 		resp := common.CreateFailResponse("Film is not currently playing", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
+
 	// Check Area by ID
-	area, err := sessionC.areaService.GetAreaByID(ctx, sessionDTO.AreaID)
+	area, err := sessionC.areaService.GetAreaByID(ctx, scheduleDTO.AreaID)
 	if err != nil {
 		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -105,19 +166,141 @@ func (sessionC *sessionController) CreateSession(ctx *gin.Context) {
 		return
 	}
 
-	_, err = sessionC.sessionService.CreateNewSession(ctx, sessionDTO, area.SpotCount, area.SpotPerRow)
+	if scheduleDTO.Price.IsNegative() || !scheduleDTO.Price.HasSupportedCurrency() || (scheduleDTO.Price.IsZero() && !area.BasePrice.IsPositive()) {
+		resp := common.CreateFailResponse("session price invalid", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	schedule, err := sessionC.sessionService.ScheduleSessions(ctx, scheduleDTO, film, area)
 	if err != nil {
-		resp := common.CreateFailResponse("failed to process session create request", http.StatusBadRequest)
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
-	resp := common.CreateEmptySuccessResponse("successfully created session", http.StatusCreated)
+	resp := common.CreateSuccessResponse("successfully scheduled sessions", http.StatusCreated, schedule)
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+func (sessionC *sessionController) CheckSessionConflicts(ctx *gin.Context) {
+	filmID, err := strconv.ParseUint(ctx.Query("film_id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process film id of session conflict request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	areaID, err := strconv.ParseUint(ctx.Query("area_id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process area id of session conflict request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, ctx.Query("time"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process session time", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	film, err := sessionC.filmService.GetFilmByID(ctx, filmID)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(film, entity.Film{}) {
+		resp := common.CreateFailResponse("film not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	area, err := sessionC.areaService.GetAreaByID(ctx, areaID)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(area, entity.Area{}) {
+		resp := common.CreateFailResponse("area not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	conflictCheck, err := sessionC.sessionService.CheckSessionConflicts(ctx, film, area, startsAt)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to check session conflicts", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(conflictCheck.Conflicts) == 0 {
+		resp = common.CreateSuccessResponse("no conflicting session found", http.StatusOK, conflictCheck)
+	} else {
+		resp = common.CreateSuccessResponse("session overlaps another session in the area", http.StatusOK, conflictCheck)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (sessionC *sessionController) GetShowtimes(ctx *gin.Context) {
+	date := time.Now().In(common.Location())
+	if ctx.Query("date") != "" {
+		var err error
+		date, err = time.ParseInLocation("2006-01-02", ctx.Query("date"), common.Location())
+		if err != nil {
+			resp := common.CreateFailResponse("date must use the YYYY-MM-DD format", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	var areaID uint64
+	if ctx.Query("area") != "" {
+		var err error
+		areaID, err = strconv.ParseUint(ctx.Query("area"), 10, 64)
+		if err != nil {
+			resp := common.CreateFailResponse("failed to process area of showtimes request", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	showtimes, err := sessionC.sessionService.GetShowtimes(ctx, date, ctx.Query("film"), areaID)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to fetch showtimes", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if len(showtimes.Films) == 0 {
+		resp = common.CreateSuccessResponse("no showtime found", http.StatusOK, showtimes)
+	} else {
+		resp = common.CreateSuccessResponse("successfully fetched showtimes", http.StatusOK, showtimes)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+var sessionQueryOptions = common.QueryOptions{
+	SortFields:   map[string]string{"id": "id", "starts_at": "starts_at", "created_at": "created_at"},
+	FilterFields: map[string]string{"film_id": "film_id", "area_id": "area_id"},
+	DefaultSort:  "starts_at",
+}
+
 func (sessionC *sessionController) GetAllSessions(ctx *gin.Context) {
-	sessions, err := sessionC.sessionService.GetAllSessions(ctx)
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), sessionQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	sessions, meta, err := sessionC.sessionService.GetAllSessions(ctx, query)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to fetch all sessions", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -126,9 +309,9 @@ func (sessionC *sessionController) GetAllSessions(ctx *gin.Context) {
 
 	var resp common.Response
 	if len(sessions) == 0 {
-		resp = common.CreateSuccessResponse("no session found", http.StatusOK, sessions)
+		resp = common.CreatePaginatedResponse("no session found", http.StatusOK, sessions, meta)
 	} else {
-		resp = common.CreateSuccessResponse("successfully fetched all sessions", http.StatusOK, sessions)
+		resp = common.CreatePaginatedResponse("successfully fetched all sessions", http.StatusOK, sessions, meta)
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -237,3 +420,183 @@ func (sessionC *sessionController) GetSessionDetailByID(ctx *gin.Context) {
 	resp := common.CreateSuccessResponse("successfully fetched session", http.StatusOK, session)
 	ctx.JSON(http.StatusOK, resp)
 }
+
+func (sessionC *sessionController) GetSessionSeatMap(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of get seat map request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	session, err := sessionC.sessionService.GetSessionByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get session by id of get seat map request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(session, entity.Session{}) {
+		resp := common.CreateFailResponse("session with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	area, err := sessionC.areaService.GetAreaByID(ctx, session.AreaID)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get area of get seat map request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	seatMap, err := sessionC.sessionService.GetSessionSeatMap(ctx, session, area)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process seat map get request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully fetched seat map", http.StatusOK, seatMap)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// StreamSeatEvents streams seat status changes of a session as Server-Sent
+// Events, starting with the whole seat map
+func (sessionC *sessionController) StreamSeatEvents(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of seat stream request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	session, err := sessionC.sessionService.GetSessionByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get session by id of seat stream request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(session, entity.Session{}) {
+		resp := common.CreateFailResponse("session with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	area, err := sessionC.areaService.GetAreaByID(ctx, session.AreaID)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get area of seat stream request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Subscribe before reading the seat map, so no change falls in between
+	events, unsubscribe := sessionC.seatEventService.Subscribe(session.ID)
+	defer unsubscribe()
+
+	seatMap, err := sessionC.sessionService.GetSessionSeatMap(ctx, session, area)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process seat stream request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.SSEvent("seatmap", seatMap)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Too far behind, the client reconnects and reloads the seat map
+				return false
+			}
+			ctx.SSEvent("seats", event)
+			return true
+		case <-heartbeat.C:
+			ctx.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func (sessionC *sessionController) HoldSpots(ctx *gin.Context) {
+	var spotDTO dto.SpotHoldRequest
+	err := ctx.ShouldBind(&spotDTO)
+	if err != nil || len(spotDTO.SpotsName) == 0 {
+		resp := common.CreateFailResponse("failed to process spot hold request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of spot hold request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	session, err := sessionC.sessionService.GetSessionByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process spot hold request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(session, entity.Session{}) {
+		resp := common.CreateFailResponse("session with given id not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if session.HasStarted(time.Now()) {
+		resp := common.CreateFailResponse("session has already started", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	userID := ctx.GetUint64("ID")
+	spots, err := sessionC.spotService.HoldSpots(ctx, id, userID, spotDTO.SpotsName)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully held spots", http.StatusOK, spots)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (sessionC *sessionController) ReleaseSpotHolds(ctx *gin.Context) {
+	var spotDTO dto.SpotHoldRequest
+	err := ctx.ShouldBind(&spotDTO)
+	if err != nil || len(spotDTO.SpotsName) == 0 {
+		resp := common.CreateFailResponse("failed to process spot release request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id of spot release request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	userID := ctx.GetUint64("ID")
+	err = sessionC.spotService.ReleaseSpotHolds(ctx, id, userID, spotDTO.SpotsName)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully released spots", http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, resp)
+}
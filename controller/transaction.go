@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,7 +18,6 @@ import (
 type transactionController struct {
 	transactionService service.TransactionService
 	sessionService     service.SessionService
-	spotService        service.SpotService
 	userService        service.UserService
 }
 
@@ -26,13 +27,17 @@ type TransactionController interface {
 	GetTransactionsByUsername(ctx *gin.Context)
 	GetMyTransactions(ctx *gin.Context)
 	DeleteTransactionByID(ctx *gin.Context)
+	PayMyTransaction(ctx *gin.Context)
+	CancelMyTransaction(ctx *gin.Context)
+	ConfirmTransaction(ctx *gin.Context)
+	CancelTransaction(ctx *gin.Context)
+	RefundTransaction(ctx *gin.Context)
 }
 
-func NewTransactionController(transactionS service.TransactionService, sessionS service.SessionService, spotS service.SpotService, userS service.UserService) TransactionController {
+func NewTransactionController(transactionS service.TransactionService, sessionS service.SessionService, userS service.UserService) TransactionController {
 	return &transactionController{
 		transactionService: transactionS,
 		sessionService:     sessionS,
-		spotService:        spotS,
 		userService:        userS,
 	}
 }
@@ -46,6 +51,12 @@ func (transactionC *transactionController) MakeTransaction(ctx *gin.Context) {
 		return
 	}
 
+	if len(transactionDTO.SpotsName) == 0 {
+		resp := common.CreateFailResponse("no spot selected", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
 	userId := ctx.GetUint64("ID")
 	transactionDTO.UserID = userId
 
@@ -68,60 +79,23 @@ func (transactionC *transactionController) MakeTransaction(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, resp)
 		return
 	}
-	transactionDTO.SessionID = sessionId
-
-	var spots []entity.Spot
-	for _, spotName := range transactionDTO.SpotsName {
-		spotNum, err := strconv.ParseInt(spotName[1:], 10, 0)
-		if err != nil {
-			resp := common.CreateFailResponse("failed to process spot name", http.StatusBadRequest)
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
-			return
-		}
 
-		spot, err := transactionC.spotService.GetSpotBySessionIDAndAttributes(ctx, sessionId, string(spotName[0]), int(spotNum))
-		if err != nil {
-			resp := common.CreateFailResponse("failed to process spot", http.StatusBadRequest)
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
-			return
-		}
-
-		if reflect.DeepEqual(spot, entity.Spot{}) {
-			resp := common.CreateFailResponse("spot with name "+spotName+" not found", http.StatusBadRequest)
-			ctx.JSON(http.StatusBadRequest, resp)
-			return
-		}
-
-		if spot.TransactionID != nil {
-			resp := common.CreateFailResponse("spot with name "+spotName+" is reserved", http.StatusBadRequest)
-			ctx.JSON(http.StatusBadRequest, resp)
-			return
-		}
-
-		spots = append(spots, spot)
+	if session.HasStarted(time.Now()) {
+		resp := common.CreateFailResponse("session has already started", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
 	}
+	transactionDTO.SessionID = sessionId
 
-	transactionDTO.TotalPrice = (session.Price * float64(len(transactionDTO.SpotsName)))
 	transactionDTO.Code = uuid.NewString()
 
-	newTransaction, err := transactionC.transactionService.CreateNewTransaction(ctx, transactionDTO)
+	newTransaction, err := transactionC.transactionService.MakeTransaction(ctx, transactionDTO)
 	if err != nil {
-		resp := common.CreateFailResponse("failed to process transaction make request", http.StatusBadRequest)
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
 
-	for _, spot := range spots {
-		spot.TransactionID = &newTransaction.ID
-
-		_, err = transactionC.spotService.UpdateSpot(ctx, spot)
-		if err != nil {
-			resp := common.CreateFailResponse("failed to reserve spot "+spot.Row+strconv.Itoa(spot.Number), http.StatusBadRequest)
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
-			return
-		}
-	}
-
 	transaction, err := transactionC.transactionService.GetTransactionByID(ctx, newTransaction.ID)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to process transaction make request", http.StatusBadRequest)
@@ -133,8 +107,21 @@ func (transactionC *transactionController) MakeTransaction(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+var transactionQueryOptions = common.QueryOptions{
+	SortFields:   map[string]string{"id": "id", "created_at": "created_at", "total_price": "total_price_amount", "status": "status"},
+	FilterFields: map[string]string{"status": "status", "user_id": "user_id", "session_id": "session_id"},
+	DefaultSort:  "-created_at",
+}
+
 func (transactionC *transactionController) GetAllTransactions(ctx *gin.Context) {
-	transactions, err := transactionC.transactionService.GetAllTransactions(ctx)
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), transactionQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactions, meta, err := transactionC.transactionService.GetAllTransactions(ctx, query)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to fetch all transactions", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -143,9 +130,9 @@ func (transactionC *transactionController) GetAllTransactions(ctx *gin.Context)
 
 	var resp common.Response
 	if len(transactions) == 0 {
-		resp = common.CreateSuccessResponse("no transaction found", http.StatusOK, transactions)
+		resp = common.CreatePaginatedResponse("no transaction found", http.StatusOK, transactions, meta)
 	} else {
-		resp = common.CreateSuccessResponse("successfully fetched all transactions", http.StatusOK, transactions)
+		resp = common.CreatePaginatedResponse("successfully fetched all transactions", http.StatusOK, transactions, meta)
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -224,7 +211,7 @@ func (transactionC *transactionController) DeleteTransactionByID(ctx *gin.Contex
 
 	err = transactionC.transactionService.DeleteTransactionByID(ctx, id)
 	if err != nil {
-		resp := common.CreateFailResponse("failed to delete transaction", http.StatusBadRequest)
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
@@ -232,3 +219,151 @@ func (transactionC *transactionController) DeleteTransactionByID(ctx *gin.Contex
 	resp := common.CreateSuccessResponse("successfully deleted transaction", http.StatusOK, nil)
 	ctx.JSON(http.StatusOK, resp)
 }
+
+func (transactionC *transactionController) PayMyTransaction(ctx *gin.Context) {
+	code := ctx.Param("code")
+	userID := ctx.GetUint64("ID")
+
+	transaction, err := transactionC.transactionService.GetTransactionByCode(ctx, code)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get transaction", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(transaction, entity.Transaction{}) || transaction.UserID != userID {
+		resp := common.CreateFailResponse("transaction with given code not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	_, err = transactionC.transactionService.PayTransaction(ctx, transaction.ID)
+	if errors.Is(err, service.ErrPaymentProcessing) {
+		transaction, err = transactionC.transactionService.GetTransactionByID(ctx, transaction.ID)
+		if err != nil {
+			resp := common.CreateFailResponse("failed to get transaction", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+
+		resp := common.CreateSuccessResponse("payment is being processed", http.StatusAccepted, transaction)
+		ctx.JSON(http.StatusAccepted, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactionC.respondWithTransaction(ctx, transaction.ID, "successfully paid transaction")
+}
+
+func (transactionC *transactionController) CancelMyTransaction(ctx *gin.Context) {
+	var transactionDTO dto.TransactionCancelRequest
+	err := ctx.ShouldBind(&transactionDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process transaction cancel request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	code := ctx.Param("code")
+	userID := ctx.GetUint64("ID")
+
+	transaction, err := transactionC.transactionService.GetTransactionByCode(ctx, code)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get transaction", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(transaction, entity.Transaction{}) || transaction.UserID != userID {
+		resp := common.CreateFailResponse("transaction with given code not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	_, err = transactionC.transactionService.CancelMyTransaction(ctx, transaction.ID, transactionDTO.Reason)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactionC.respondWithTransaction(ctx, transaction.ID, "successfully cancelled transaction")
+}
+
+func (transactionC *transactionController) ConfirmTransaction(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var transactionDTO dto.TransactionConfirmRequest
+	err = ctx.ShouldBind(&transactionDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process transaction confirm request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	_, err = transactionC.transactionService.ConfirmTransaction(ctx, id, transactionDTO.PaymentReference)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactionC.respondWithTransaction(ctx, id, "successfully confirmed transaction payment")
+}
+
+func (transactionC *transactionController) CancelTransaction(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	_, err = transactionC.transactionService.CancelTransaction(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactionC.respondWithTransaction(ctx, id, "successfully cancelled transaction")
+}
+
+func (transactionC *transactionController) RefundTransaction(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process id", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	_, err = transactionC.transactionService.RefundTransaction(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transactionC.respondWithTransaction(ctx, id, "successfully refunded transaction")
+}
+
+func (transactionC *transactionController) respondWithTransaction(ctx *gin.Context, id uint64, msg string) {
+	transaction, err := transactionC.transactionService.GetTransactionByID(ctx, id)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to get transaction", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse(msg, http.StatusOK, transaction)
+	ctx.JSON(http.StatusOK, resp)
+}
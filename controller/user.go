@@ -1,35 +1,51 @@
 package controller
 
 import (
+	"errors"
 	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/service"
+	"io"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type userController struct {
-	userService service.UserService
-	jwtService  service.JWTService
+	userService          service.UserService
+	authService          service.AuthService
+	passwordResetService service.PasswordResetService
 }
 
 type UserController interface {
 	Register(ctx *gin.Context)
 	Login(ctx *gin.Context)
+	Refresh(ctx *gin.Context)
+	Logout(ctx *gin.Context)
 	GetAllUsers(ctx *gin.Context)
 	GetUserByUsername(ctx *gin.Context)
 	GetMe(ctx *gin.Context)
 	UpdateSelfName(ctx *gin.Context)
+	UpdateSelfBirthDate(ctx *gin.Context)
+	UpdateSelfPassword(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	RecoverPassword(ctx *gin.Context)
 	DeleteSelfUser(ctx *gin.Context)
+	CreateUser(ctx *gin.Context)
+	UpdateUserByUsername(ctx *gin.Context)
+	SuspendUser(ctx *gin.Context)
+	ReactivateUser(ctx *gin.Context)
+	ResetUserPassword(ctx *gin.Context)
 }
 
-func NewUserController(userS service.UserService, jwtS service.JWTService) UserController {
+func NewUserController(userS service.UserService, authS service.AuthService, passwordResetS service.PasswordResetService) UserController {
 	return &userController{
-		userService: userS,
-		jwtService:  jwtS,
+		userService:          userS,
+		authService:          authS,
+		passwordResetService: passwordResetS,
 	}
 }
 
@@ -42,6 +58,14 @@ func (userC *userController) Register(ctx *gin.Context) {
 		return
 	}
 
+	if userDTO.BirthDate != "" {
+		if _, err := service.ParseBirthDate(userDTO.BirthDate, time.Now()); err != nil {
+			resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
 	// Check for duplicate Username or Email
 	userCheck, err := userC.userService.GetUserByUsernameOrEmail(ctx, userDTO.Username, userDTO.Email)
 	if err != nil {
@@ -52,14 +76,7 @@ func (userC *userController) Register(ctx *gin.Context) {
 
 	// Check if duplicate is found
 	if !(reflect.DeepEqual(userCheck, entity.User{})) {
-		var resp common.Response
-		if userCheck.Username == userDTO.Username && userCheck.Email == userDTO.Email {
-			resp = common.CreateFailResponse("username and email are already used", http.StatusBadRequest)
-		} else if userCheck.Username == userDTO.Username {
-			resp = common.CreateFailResponse("username is already used", http.StatusBadRequest)
-		} else if userCheck.Email == userDTO.Email {
-			resp = common.CreateFailResponse("email is already used", http.StatusBadRequest)
-		}
+		resp := common.CreateFailResponse(duplicateUserMessage(userCheck, userDTO.Username, userDTO.Email), http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
 		return
 	}
@@ -75,6 +92,16 @@ func (userC *userController) Register(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+// duplicateUserMessage tells which of username and email another user has
+func duplicateUserMessage(userCheck entity.User, username string, email string) string {
+	if userCheck.Username == username && userCheck.Email == email {
+		return "username and email are already used"
+	} else if userCheck.Username == username {
+		return "username is already used"
+	}
+	return "email is already used"
+}
+
 func (userC *userController) Login(ctx *gin.Context) {
 	var userDTO dto.UserLoginRequest
 	err := ctx.ShouldBind(&userDTO)
@@ -98,14 +125,69 @@ func (userC *userController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token := userC.jwtService.GenerateToken(user.ID, user.Role)
-	authResp := common.CreateAuthResponse(token, user.Role)
+	authResp, err := userC.authService.IssueTokens(ctx.Request.Context(), user, userDTO.DeviceID)
+	if errors.Is(err, service.ErrUserSuspended) {
+		response := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, response)
+		return
+	}
+	if err != nil {
+		response := common.CreateFailResponse("failed to process user login request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, response)
+		return
+	}
+
 	resp := common.CreateSuccessResponse("user login successful", http.StatusOK, authResp)
 	ctx.JSON(http.StatusOK, resp)
 }
 
+func (userC *userController) Refresh(ctx *gin.Context) {
+	var refreshDTO dto.UserRefreshRequest
+	err := ctx.ShouldBind(&refreshDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process token refresh request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	authResp, err := userC.authService.RefreshTokens(ctx.Request.Context(), refreshDTO.RefreshToken, refreshDTO.DeviceID)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusUnauthorized)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("token refresh successful", http.StatusOK, authResp)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) Logout(ctx *gin.Context) {
+	err := userC.authService.Logout(ctx.Request.Context(), ctx.GetString("FamilyID"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user logout request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateEmptySuccessResponse("user logout successful", http.StatusOK)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+var userQueryOptions = common.QueryOptions{
+	SortFields:   map[string]string{"id": "id", "name": "name", "username": "username", "email": "email", "created_at": "created_at"},
+	FilterFields: map[string]string{"role": "role"},
+	DefaultSort:  "id",
+}
+
 func (userC *userController) GetAllUsers(ctx *gin.Context) {
-	users, err := userC.userService.GetAllUsers(ctx)
+	query, err := common.ParseQuery(ctx.Request.URL.Query(), userQueryOptions)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	users, meta, err := userC.userService.GetAllUsers(ctx, query)
 	if err != nil {
 		resp := common.CreateFailResponse("failed to fetch all users", http.StatusBadRequest)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
@@ -114,9 +196,9 @@ func (userC *userController) GetAllUsers(ctx *gin.Context) {
 
 	var resp common.Response
 	if len(users) == 0 {
-		resp = common.CreateSuccessResponse("no user found", http.StatusOK, users)
+		resp = common.CreatePaginatedResponse("no user found", http.StatusOK, users, meta)
 	} else {
-		resp = common.CreateSuccessResponse("successfully fetched all users", http.StatusOK, users)
+		resp = common.CreatePaginatedResponse("successfully fetched all users", http.StatusOK, users, meta)
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -183,6 +265,114 @@ func (userC *userController) UpdateSelfName(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
+func (userC *userController) UpdateSelfBirthDate(ctx *gin.Context) {
+	var userDTO dto.UserBirthDateUpdateRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user birth date update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	id := ctx.GetUint64("ID")
+	user, err := userC.userService.UpdateSelfBirthDate(ctx, userDTO, id)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp = common.CreateSuccessResponse("user not found", http.StatusOK, nil)
+	} else {
+		resp = common.CreateSuccessResponse("successfully updated user", http.StatusOK, user)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) UpdateSelfPassword(ctx *gin.Context) {
+	var userDTO dto.UserPasswordUpdateRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user password update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	id := ctx.GetUint64("ID")
+	user, err := userC.userService.UpdateSelfPassword(ctx, userDTO, id)
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateSuccessResponse("user not found", http.StatusOK, nil)
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// Log out every other device, this one stays logged in
+	err = userC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, ctx.GetString("FamilyID"))
+	if err != nil {
+		resp := common.CreateFailResponse("password was updated but failed to log out other devices", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateEmptySuccessResponse("successfully updated password", http.StatusOK)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) ForgotPassword(ctx *gin.Context) {
+	var userDTO dto.UserForgotPasswordRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process forgot password request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	userC.passwordResetService.RequestPasswordReset(ctx.Request.Context(), userDTO.UserIdentifier)
+	resp := common.CreateEmptySuccessResponse("a password reset link has been sent if the account exists", http.StatusOK)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) RecoverPassword(ctx *gin.Context) {
+	var userDTO dto.UserRecoverPasswordRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process password reset request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err := userC.passwordResetService.ResetPassword(ctx.Request.Context(), userDTO)
+	if errors.Is(err, service.ErrPasswordResetTokenInvalid) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process password reset request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Whoever knew the old password must not stay logged in
+	err = userC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, "")
+	if err != nil {
+		resp := common.CreateFailResponse("password was reset but failed to log out other devices", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateEmptySuccessResponse("successfully reset password", http.StatusOK)
+	ctx.JSON(http.StatusOK, resp)
+}
+
 func (userC *userController) DeleteSelfUser(ctx *gin.Context) {
 	id := ctx.GetUint64("ID")
 	err := userC.userService.DeleteSelfUser(ctx, id)
@@ -195,3 +385,236 @@ func (userC *userController) DeleteSelfUser(ctx *gin.Context) {
 	resp := common.CreateSuccessResponse("successfully deleted user", http.StatusOK, nil)
 	ctx.JSON(http.StatusOK, resp)
 }
+
+// editorClaims returns the user behind the access token of the request,
+// for the service to check what they may do to other users
+func editorClaims(ctx *gin.Context) service.AccessClaims {
+	return service.AccessClaims{
+		UserID:      ctx.GetUint64("ID"),
+		Permissions: ctx.GetStringSlice("Permissions"),
+		FamilyID:    ctx.GetString("FamilyID"),
+	}
+}
+
+func (userC *userController) CreateUser(ctx *gin.Context) {
+	var userDTO dto.UserCreateRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Check for duplicate Username or Email
+	userCheck, err := userC.userService.GetUserByUsernameOrEmail(ctx, userDTO.Username, userDTO.Email)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user create request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if !(reflect.DeepEqual(userCheck, entity.User{})) {
+		resp := common.CreateFailResponse(duplicateUserMessage(userCheck, userDTO.Username, userDTO.Email), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err := userC.userService.CreateUser(ctx, userDTO, editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully created user", http.StatusCreated, user)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (userC *userController) UpdateUserByUsername(ctx *gin.Context) {
+	var userDTO dto.UserUpdateRequest
+	err := ctx.ShouldBind(&userDTO)
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err := userC.userService.GetUserByIdentifier(ctx, ctx.Param("username"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user update request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateFailResponse("user with given username not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if userDTO.Email != "" && userDTO.Email != user.Email {
+		userCheck, err := userC.userService.GetUserByUsernameOrEmail(ctx, userDTO.Email, userDTO.Email)
+		if err != nil {
+			resp := common.CreateFailResponse("failed to process user update request", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+
+		if !(reflect.DeepEqual(userCheck, entity.User{})) && userCheck.ID != user.ID {
+			resp := common.CreateFailResponse("email is already used", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	oldRole := user.Role
+	user, err = userC.userService.UpdateUser(ctx, userDTO, user, editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Access tokens carry the permissions of the role they were issued with
+	if user.Role != oldRole {
+		err = userC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, "")
+		if err != nil {
+			resp := common.CreateFailResponse("user was updated but failed to log them out", http.StatusBadRequest)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	resp := common.CreateSuccessResponse("successfully updated user", http.StatusOK, user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) SuspendUser(ctx *gin.Context) {
+	user, err := userC.userService.GetUserByIdentifier(ctx, ctx.Param("username"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user suspend request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateFailResponse("user with given username not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err = userC.userService.SuspendUser(ctx, user, editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Log the user out of every device so the suspension applies right away
+	err = userC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, "")
+	if err != nil {
+		resp := common.CreateFailResponse("user was suspended but failed to log them out", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully suspended user", http.StatusOK, user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) ReactivateUser(ctx *gin.Context) {
+	user, err := userC.userService.GetUserByIdentifier(ctx, ctx.Param("username"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user reactivate request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateFailResponse("user with given username not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err = userC.userService.ReactivateUser(ctx, user, editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse(err.Error(), http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	resp := common.CreateSuccessResponse("successfully reactivated user", http.StatusOK, user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (userC *userController) ResetUserPassword(ctx *gin.Context) {
+	// The body can be left out to make up a random password
+	var passwordDTO dto.UserPasswordResetRequest
+	err := ctx.ShouldBind(&passwordDTO)
+	if err != nil && !errors.Is(err, io.EOF) {
+		resp := common.CreateFailResponse("failed to process user password reset request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, err := userC.userService.GetUserByIdentifier(ctx, ctx.Param("username"))
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user password reset request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		resp := common.CreateFailResponse("user with given username not found", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	password, err := userC.userService.ResetUserPassword(ctx, passwordDTO.Password, user, editorClaims(ctx))
+	if errors.Is(err, service.ErrUserPermissionDenied) {
+		resp := common.CreateFailResponse(err.Error(), http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		resp := common.CreateFailResponse("failed to process user password reset request", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Whoever knew the old password must not stay logged in
+	err = userC.authService.RevokeUserTokens(ctx.Request.Context(), user.ID, "")
+	if err != nil {
+		resp := common.CreateFailResponse("password was reset but failed to log the user out", http.StatusBadRequest)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	var resp common.Response
+	if passwordDTO.Password == "" {
+		resp = common.CreateSuccessResponse("successfully reset user password", http.StatusOK, dto.UserPasswordResetResponse{Password: password})
+	} else {
+		resp = common.CreateEmptySuccessResponse("successfully reset user password", http.StatusOK)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -1,7 +1,15 @@
 package dto
 
+import "fp-rpl/common"
+
 type AreaCreateRequest struct {
-	Name       string `json:"name" binding:"required"`
-	SpotCount  int    `json:"spot_count" binding:"required"`
-	SpotPerRow int    `json:"spot_per_row" binding:"required"`
-}
\ No newline at end of file
+	Name              string       `json:"name" binding:"required"`
+	SpotCount         int          `json:"spot_count" binding:"required"`
+	SpotPerRow        int          `json:"spot_per_row" binding:"required"`
+	BasePrice         common.Money `json:"base_price"`
+	TurnaroundMinutes int          `json:"turnaround_minutes"`
+}
+
+type AreaLayoutRequest struct {
+	Layout []string `json:"layout" binding:"required"`
+}
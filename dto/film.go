@@ -1,20 +1,45 @@
 package dto
 
 type FilmRegisterRequest struct {
-	Title      string     `json:"title" binding:"required"`
-	Slug       string     `json:"slug" binding:"required"`
-	Synopsis   string     `json:"synopsis" binding:"required"`
-	Duration   int        `json:"duration" binding:"required"`
-	Genre      string     `json:"genre" binding:"required"`
-	Producer   string     `json:"producer" binding:"required"`
-	Director   string     `json:"director" binding:"required"`
-	Writer     string     `json:"writer" binding:"required"`
-	Production string     `json:"production" binding:"required"`
-	Cast       string     `json:"cast" binding:"required"`
-	Trailer    string     `json:"trailer" binding:"required"`
-	Image      string     `json:"image" binding:"required"`
-	Status     string     `json:"status"`
-	StatusCode FilmStatus `json:"status_code" binding:"required"`
+	Title      string `json:"title" binding:"required"`
+	Slug       string `json:"slug" binding:"required"`
+	Synopsis   string `json:"synopsis" binding:"required"`
+	Duration   int    `json:"duration" binding:"required"`
+	Production string `json:"production" binding:"required"`
+	// Trailer links to a trailer hosted elsewhere, uploading a trailer sets
+	// it. It is left as it is on update when empty.
+	Trailer string `json:"trailer"`
+	// Image links to a poster hosted elsewhere, uploading a poster sets it.
+	// It is left as it is on update when empty.
+	Image  string `json:"image"`
+	Status string `json:"status"`
+	// StatusCode is only required when the status isn't set from the release date
+	StatusCode FilmStatus `json:"status_code"`
+	// ReleaseDate and EndDate use the YYYY-MM-DD format, EndDate being the
+	// last day the film plays
+	ReleaseDate    string `json:"release_date"`
+	EndDate        string `json:"end_date"`
+	StatusOverride bool   `json:"status_override"`
+	// AgeRating is one of SU, 13+, 17+ or 21+, SU when empty
+	AgeRating string `json:"age_rating"`
+	// GenreIDs and Credits are left as they are on update when omitted, an
+	// empty list clears them
+	GenreIDs []uint64            `json:"genre_ids"`
+	Credits  []FilmCreditRequest `json:"credits"`
+}
+
+type FilmCreditRequest struct {
+	PersonID  uint64 `json:"person_id" binding:"required"`
+	Role      string `json:"role" binding:"required"`
+	Character string `json:"character"`
+}
+
+// FilmFilter narrows the film list down to a genre, or to the films of a
+// person, optionally in a single role
+type FilmFilter struct {
+	Genre    string
+	PersonID uint64
+	Role     string
 }
 
 type FilmStatus int64
@@ -35,4 +60,4 @@ func (s FilmStatus) String() string {
 		return "Not Playing"
 	}
 	return "Unknown"
-}
\ No newline at end of file
+}
@@ -0,0 +1,5 @@
+package dto
+
+type GenreCreateRequest struct {
+	Name string `json:"name" binding:"required"`
+}
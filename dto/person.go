@@ -0,0 +1,8 @@
+package dto
+
+type PersonCreateRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Slug is made from the name when left empty
+	Slug      string `json:"slug"`
+	Biography string `json:"biography"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "fp-rpl/common"
+
+type PriceRuleCreateRequest struct {
+	Name      string       `json:"name" binding:"required"`
+	SpotType  string       `json:"spot_type"`
+	AreaID    *uint64      `json:"area_id"`
+	DayType   string       `json:"day_type"`
+	StartTime string       `json:"start_time"`
+	EndTime   string       `json:"end_time"`
+	Amount    common.Money `json:"amount"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"fp-rpl/common"
+	"time"
+)
+
+type PromoCodeCreateRequest struct {
+	Code           string       `json:"code" binding:"required"`
+	DiscountType   string       `json:"discount_type" binding:"required"`
+	Percentage     float64      `json:"percentage"`
+	Amount         common.Money `json:"amount"`
+	MinSpots       int          `json:"min_spots"`
+	ValidFrom      *time.Time   `json:"valid_from"`
+	ValidUntil     *time.Time   `json:"valid_until"`
+	MaxUses        int          `json:"max_uses"`
+	MaxUsesPerUser int          `json:"max_uses_per_user"`
+	FilmID         *uint64      `json:"film_id"`
+	AreaID         *uint64      `json:"area_id"`
+}
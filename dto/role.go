@@ -0,0 +1,17 @@
+package dto
+
+type RoleCreateRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleUpdateRequest struct {
+	Description string `json:"description"`
+	// Permissions are left as they are when omitted, an empty list clears them
+	Permissions []string `json:"permissions"`
+}
+
+type UserRoleUpdateRequest struct {
+	Role string `json:"role" binding:"required"`
+}
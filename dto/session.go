@@ -1,8 +1,80 @@
 package dto
 
+import (
+	"fp-rpl/common"
+	"time"
+)
+
 type SessionCreateRequest struct {
-	Time   string  `json:"time" binding:"required"`
-	Price  float64 `json:"price" binding:"required"`
-	FilmID uint64  `json:"film_id" binding:"required"`
-	AreaID uint64  `json:"area_id" binding:"required"`
-}
\ No newline at end of file
+	Time   string       `json:"time" binding:"required"`
+	Price  common.Money `json:"price"`
+	FilmID uint64       `json:"film_id" binding:"required"`
+	AreaID uint64       `json:"area_id" binding:"required"`
+}
+
+// SessionScheduleRequest creates a session at every showtime of every
+// matching day between StartDate and EndDate, both inclusive
+type SessionScheduleRequest struct {
+	FilmID    uint64       `json:"film_id" binding:"required"`
+	AreaID    uint64       `json:"area_id" binding:"required"`
+	StartDate string       `json:"start_date" binding:"required"`
+	EndDate   string       `json:"end_date" binding:"required"`
+	Days      []string     `json:"days"`
+	Showtimes []string     `json:"showtimes" binding:"required"`
+	Price     common.Money `json:"price"`
+}
+
+type SessionConflict struct {
+	SessionID uint64    `json:"session_id"`
+	FilmID    uint64    `json:"film_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// SessionConflictResponse tells whether a session could be created, EndsAt
+// includes the turnaround of the area
+type SessionConflictResponse struct {
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	Conflicts []SessionConflict `json:"conflicts"`
+}
+
+type SessionScheduleSlot struct {
+	Time      string `json:"time"`
+	SessionID uint64 `json:"session_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type SessionScheduleResponse struct {
+	Created []SessionScheduleSlot `json:"created"`
+	Skipped []SessionScheduleSlot `json:"skipped"`
+}
+
+type ShowtimesResponse struct {
+	Date  string         `json:"date"`
+	Films []ShowtimeFilm `json:"films"`
+}
+
+type ShowtimeFilm struct {
+	ID        uint64         `json:"id"`
+	Title     string         `json:"title"`
+	Slug      string         `json:"slug"`
+	Duration  int            `json:"duration"`
+	AgeRating string         `json:"age_rating"`
+	Image     string         `json:"image"`
+	Areas     []ShowtimeArea `json:"areas"`
+}
+
+type ShowtimeArea struct {
+	ID       uint64            `json:"id"`
+	Name     string            `json:"name"`
+	Sessions []ShowtimeSession `json:"sessions"`
+}
+
+type ShowtimeSession struct {
+	ID             uint64       `json:"id"`
+	StartsAt       time.Time    `json:"starts_at"`
+	EndsAt         time.Time    `json:"ends_at"`
+	Price          common.Money `json:"price"`
+	RemainingSeats int          `json:"remaining_seats"`
+}
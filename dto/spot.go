@@ -0,0 +1,33 @@
+package dto
+
+type SpotHoldRequest struct {
+	SpotsName []string `json:"spots_name" binding:"required"`
+}
+
+const (
+	SeatFree    = "free"
+	SeatHeld    = "held"
+	SeatSold    = "sold"
+	SeatBlocked = "blocked"
+)
+
+type SeatMapResponse struct {
+	SessionID uint64           `json:"session_id"`
+	Rows      int              `json:"rows"`
+	Columns   int              `json:"columns"`
+	Seats     [][]*SeatMapSeat `json:"seats"`
+}
+
+type SeatMapSeat struct {
+	Name   string `json:"name"`
+	Row    string `json:"row"`
+	Number int    `json:"number"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// SeatEvent carries the new status of seats of a session that changed
+type SeatEvent struct {
+	SessionID uint64        `json:"session_id"`
+	Seats     []SeatMapSeat `json:"seats"`
+}
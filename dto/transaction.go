@@ -1,9 +1,17 @@
 package dto
 
 type TransactionMakeRequest struct {
-	Code       string
-	TotalPrice float64
-	SpotsName  []string `json:"spots_name" binding:"required"`
-	UserID     uint64
-	SessionID  uint64
-}
\ No newline at end of file
+	Code      string
+	SpotsName []string `json:"spots_name" binding:"required"`
+	PromoCode string   `json:"promo_code" copier:"-"`
+	UserID    uint64
+	SessionID uint64
+}
+
+type TransactionConfirmRequest struct {
+	PaymentReference string `json:"payment_reference" binding:"required"`
+}
+
+type TransactionCancelRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
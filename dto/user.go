@@ -7,13 +7,75 @@ type UserRegisterRequest struct {
 	Password string `json:"password" binding:"required"`
 	NoTelp   string `json:"no-telp" binding:"required"`
 	Role     string `json:"role"`
+	// BirthDate uses the YYYY-MM-DD format and is needed to book rated films
+	BirthDate string `json:"birth-date"`
 }
 
 type UserLoginRequest struct {
 	UserIdentifier string `json:"user-identifier" binding:"required"`
 	Password       string `json:"password" binding:"required"`
+	// DeviceID binds the refresh token to the device, one is made up when
+	// it is left out
+	DeviceID string `json:"device-id" binding:"max=64"`
+}
+
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh-token" binding:"required"`
+	DeviceID     string `json:"device-id" binding:"required"`
 }
 
 type UserNameUpdateRequest struct {
 	Name string `json:"name" binding:"required"`
 }
+
+type UserBirthDateUpdateRequest struct {
+	BirthDate string `json:"birth-date" binding:"required"`
+}
+
+// UserCreateRequest creates an account of any role, such as those of staff.
+// The role is user when left out.
+type UserCreateRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Email     string `json:"email" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	NoTelp    string `json:"no-telp" binding:"required"`
+	Role      string `json:"role"`
+	BirthDate string `json:"birth-date"`
+}
+
+// UserUpdateRequest changes the profile and role of any user, fields left
+// out are kept as they are
+type UserUpdateRequest struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	NoTelp    string `json:"no-telp"`
+	BirthDate string `json:"birth-date"`
+	Role      string `json:"role"`
+}
+
+// UserPasswordResetRequest sets the password of a user, a random one is
+// made up when it is left out
+type UserPasswordResetRequest struct {
+	Password string `json:"password"`
+}
+
+type UserPasswordResetResponse struct {
+	Password string `json:"password"`
+}
+
+type UserPasswordUpdateRequest struct {
+	OldPassword string `json:"old-password" binding:"required"`
+	NewPassword string `json:"new-password" binding:"required,min=8"`
+}
+
+type UserForgotPasswordRequest struct {
+	UserIdentifier string `json:"user-identifier" binding:"required"`
+}
+
+// UserRecoverPasswordRequest sets a new password with the token mailed to
+// the user
+type UserRecoverPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
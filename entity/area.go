@@ -4,8 +4,12 @@ import "fp-rpl/common"
 
 type Area struct {
 	common.Model
-	Name       string    `json:"name" binding:"required"`
-	SpotCount  int       `json:"spot_count" binding:"required"`
-	SpotPerRow int       `json:"spot_per_row" binding:"required"`
-	Sessions   []Session `json:"session,omitempty"`
+	Name       string       `json:"name" binding:"required"`
+	SpotCount  int          `json:"spot_count" binding:"required"`
+	SpotPerRow int          `json:"spot_per_row" binding:"required"`
+	Layout     string       `gorm:"type:text" json:"layout"`
+	BasePrice  common.Money `gorm:"embedded;embeddedPrefix:base_price_" json:"base_price"`
+	// TurnaroundMinutes is the cleaning buffer kept free after every session
+	TurnaroundMinutes int       `json:"turnaround_minutes"`
+	Sessions          []Session `json:"session,omitempty"`
 }
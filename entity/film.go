@@ -1,21 +1,95 @@
 package entity
 
-import "fp-rpl/common"
+import (
+	"fp-rpl/common"
+	"time"
+)
 
+const (
+	FilmNowPlaying = "Now Playing"
+	FilmComingSoon = "Coming Soon"
+	FilmNotPlaying = "Not Playing"
+)
+
+const (
+	RatingSU = "SU"
+	Rating13 = "13+"
+	Rating17 = "17+"
+	Rating21 = "21+"
+)
+
+// minimumAges maps each age rating to the age a viewer must have reached
+var minimumAges = map[string]int{
+	RatingSU: 0,
+	Rating13: 13,
+	Rating17: 17,
+	Rating21: 21,
+}
+
+func IsAgeRating(rating string) bool {
+	_, ok := minimumAges[rating]
+	return ok
+}
+
+// Film statuses follow the release and end dates when the film has a release
+// date, unless an admin overrides the status. EndDate is the last day the film
+// plays. PosterKey is the storage key of an uploaded poster, Image then links
+// to it and Thumbnails to its smaller sizes. TrailerKey is likewise the key of
+// an uploaded trailer Trailer links to.
 type Film struct {
 	common.Model
-	Title      string    `json:"title" binding:"required"`
-	Slug       string    `json:"slug" binding:"required"`
-	Synopsis   string    `json:"synopsis" binding:"required"`
-	Duration   int       `json:"duration" binding:"required"`
-	Genre      string    `json:"genre" binding:"required"`
-	Producer   string    `json:"producer" binding:"required"`
-	Director   string    `json:"director" binding:"required"`
-	Writer     string    `json:"writer" binding:"required"`
-	Production string    `json:"production" binding:"required"`
-	Cast       string    `json:"cast" binding:"required"`
-	Trailer    string    `json:"trailer" binding:"required"`
-	Image      string    `json:"image" binding:"required"`
-	Status     string    `json:"status" binding:"required"`
-	Sessions   []Session `json:"session,omitempty"`
+	Title          string            `json:"title" binding:"required"`
+	Slug           string            `json:"slug" binding:"required"`
+	Synopsis       string            `json:"synopsis" binding:"required"`
+	Duration       int               `json:"duration" binding:"required"`
+	Production     string            `json:"production" binding:"required"`
+	Trailer        string            `json:"trailer" binding:"required"`
+	TrailerKey     string            `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	Image          string            `json:"image" binding:"required"`
+	PosterKey      string            `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	Thumbnails     map[string]string `gorm:"type:jsonb;serializer:json" json:"thumbnails,omitempty"`
+	Status         string            `json:"status" binding:"required"`
+	AgeRating      string            `gorm:"type:varchar(8);not null;default:'SU'" json:"age_rating"`
+	ReleaseDate    *time.Time        `gorm:"type:date" json:"release_date"`
+	EndDate        *time.Time        `gorm:"type:date" json:"end_date"`
+	StatusOverride bool              `gorm:"not null;default:false" json:"status_override"`
+	Genres         []Genre           `gorm:"many2many:film_genres;" json:"genres,omitempty"`
+	Credits        []FilmCredit      `json:"credits,omitempty"`
+	Sessions       []Session         `json:"session,omitempty"`
+}
+
+// dayStart returns the midnight days after date in the cinema timezone
+func dayStart(date time.Time, days int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day()+days, 0, 0, 0, 0, common.Location())
+}
+
+// FollowsSchedule reports whether the status of the film is set from its
+// release and end dates instead of by hand
+func (f Film) FollowsSchedule() bool {
+	return f.ReleaseDate != nil && !f.StatusOverride
+}
+
+// ScheduledStatus returns the status the release and end dates give the film
+// at now. The film must have a release date.
+func (f Film) ScheduledStatus(now time.Time) string {
+	if now.Before(dayStart(*f.ReleaseDate, 0)) {
+		return FilmComingSoon
+	}
+	if f.EndDate != nil && !now.Before(dayStart(*f.EndDate, 1)) {
+		return FilmNotPlaying
+	}
+	return FilmNowPlaying
+}
+
+// IsPlayingAt reports whether sessions of the film can take place at t
+func (f Film) IsPlayingAt(t time.Time) bool {
+	if f.FollowsSchedule() {
+		return f.ScheduledStatus(t) == FilmNowPlaying
+	}
+	return f.Status == FilmNowPlaying
+}
+
+// MinimumAge returns the age a viewer must have reached to watch the film
+func (f Film) MinimumAge() int {
+	return minimumAges[f.AgeRating]
 }
@@ -0,0 +1,10 @@
+package entity
+
+import "fp-rpl/common"
+
+type Genre struct {
+	common.Model
+	Name  string `gorm:"uniqueIndex:idx_genres_name,where:deleted_at IS NULL" json:"name"`
+	Slug  string `gorm:"uniqueIndex:idx_genres_slug,where:deleted_at IS NULL" json:"slug"`
+	Films []Film `gorm:"many2many:film_genres;" json:"films,omitempty"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"fp-rpl/common"
+	"time"
+)
+
+// PasswordResetToken lets a user who forgot their password set a new one.
+// It is mailed to the user and only its SHA-256 hash is stored. A token can
+// be used once, and asking for another one voids those sent before.
+type PasswordResetToken struct {
+	common.Model
+	UserID    uint64     `gorm:"index;not null" json:"user_id"`
+	User      *User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
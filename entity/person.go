@@ -0,0 +1,39 @@
+package entity
+
+import "fp-rpl/common"
+
+const (
+	CreditCast     = "cast"
+	CreditDirector = "director"
+	CreditWriter   = "writer"
+	CreditProducer = "producer"
+)
+
+func IsCreditRole(role string) bool {
+	switch role {
+	case CreditCast, CreditDirector, CreditWriter, CreditProducer:
+		return true
+	}
+	return false
+}
+
+// Person is someone credited for working on films, as cast or crew
+type Person struct {
+	common.Model
+	Name      string       `json:"name"`
+	Slug      string       `gorm:"uniqueIndex:idx_people_slug,where:deleted_at IS NULL" json:"slug"`
+	Biography string       `gorm:"type:text" json:"biography"`
+	Credits   []FilmCredit `json:"credits,omitempty"`
+}
+
+// FilmCredit is the role a person had in a film. Billing orders the credits
+// of the same role, starting from 1.
+type FilmCredit struct {
+	FilmID    uint64  `gorm:"primaryKey" json:"film_id"`
+	Film      *Film   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"film,omitempty"`
+	PersonID  uint64  `gorm:"primaryKey" json:"person_id"`
+	Person    *Person `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"person,omitempty"`
+	Role      string  `gorm:"primaryKey;type:varchar(16)" json:"role"`
+	Character string  `json:"character"`
+	Billing   int     `json:"billing"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "fp-rpl/common"
+
+const (
+	DayWeekday = "weekday"
+	DayWeekend = "weekend"
+)
+
+// PriceRule adjusts the price of every seat it matches. Empty conditions
+// match anything, so a rule with only a spot type prices that seat type in
+// every area at every time.
+type PriceRule struct {
+	common.Model
+	Name      string       `json:"name" binding:"required"`
+	SpotType  string       `json:"spot_type"`
+	AreaID    *uint64      `gorm:"foreignKey" json:"area_id"`
+	Area      *Area        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"area,omitempty"`
+	DayType   string       `json:"day_type"`
+	StartTime string       `json:"start_time"`
+	EndTime   string       `json:"end_time"`
+	Amount    common.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
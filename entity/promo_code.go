@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"fp-rpl/common"
+	"time"
+)
+
+const (
+	PromoPercentage = "percentage"
+	PromoFixed      = "fixed"
+)
+
+// PromoCode discounts a booking. Zero limits and nil restrictions mean the
+// promo code is not limited in that way.
+type PromoCode struct {
+	common.Model
+	Code           string       `gorm:"uniqueIndex" json:"code"`
+	DiscountType   string       `json:"discount_type"`
+	Percentage     float64      `json:"percentage"`
+	Amount         common.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	MinSpots       int          `json:"min_spots"`
+	ValidFrom      *time.Time   `json:"valid_from"`
+	ValidUntil     *time.Time   `json:"valid_until"`
+	MaxUses        int          `json:"max_uses"`
+	MaxUsesPerUser int          `json:"max_uses_per_user"`
+	UsedCount      int          `json:"used_count"`
+	FilmID         *uint64      `gorm:"foreignKey" json:"film_id"`
+	Film           *Film        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"film,omitempty"`
+	AreaID         *uint64      `gorm:"foreignKey" json:"area_id"`
+	Area           *Area        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"area,omitempty"`
+}
+
+// Discount returns how much the promo code takes off a subtotal
+func (p *PromoCode) Discount(subtotal common.Money) common.Money {
+	discount := p.Amount
+	if p.DiscountType == PromoPercentage {
+		discount = subtotal.Percent(p.Percentage)
+	} else if !discount.SameCurrency(subtotal) {
+		return common.Money{Currency: subtotal.Currency}
+	}
+
+	if discount.Amount > subtotal.Amount {
+		return subtotal
+	}
+	return discount
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"fp-rpl/common"
+	"time"
+)
+
+// RefreshToken lets a device get new access tokens without logging in again.
+// Only the SHA-256 hash of the token is stored. Every refresh replaces the
+// token with a new one of the same family, the tokens of one login on one
+// device, and a replaced token being used again revokes the whole family.
+type RefreshToken struct {
+	common.Model
+	UserID    uint64     `gorm:"index;not null" json:"user_id"`
+	User      *User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	FamilyID  string     `gorm:"type:varchar(36);index;not null" json:"family_id"`
+	DeviceID  string     `gorm:"type:varchar(64);not null" json:"device_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// RevokedToken is an entry of the access token revocation list. Access
+// tokens issued to the token family are refused until ExpiresAt, by which
+// time they have all expired on their own.
+type RevokedToken struct {
+	FamilyID  string    `gorm:"type:varchar(36);primaryKey" json:"family_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
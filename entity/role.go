@@ -0,0 +1,89 @@
+package entity
+
+import "fp-rpl/common"
+
+const (
+	PermissionFilmsManage        = "films:manage"
+	PermissionAreasManage        = "areas:manage"
+	PermissionSessionsManage     = "sessions:manage"
+	PermissionPricingManage      = "pricing:manage"
+	PermissionTransactionsRead   = "transactions:read"
+	PermissionTransactionsManage = "transactions:manage"
+	PermissionTransactionsRefund = "transactions:refund"
+	PermissionUsersRead          = "users:read"
+	PermissionUsersManage        = "users:manage"
+	PermissionRolesManage        = "roles:manage"
+)
+
+const (
+	RoleAdmin         = "admin"
+	RoleUser          = "user"
+	RoleCashier       = "cashier"
+	RoleScheduler     = "scheduler"
+	RoleContentEditor = "content_editor"
+	RoleFinance       = "finance"
+)
+
+// Permission allows its holders to use a group of staff endpoints. Every
+// permission the code checks is listed in Permissions.
+type Permission struct {
+	common.Model
+	Name        string `gorm:"type:varchar(64);not null;uniqueIndex:idx_permissions_name,where:deleted_at IS NULL" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions given to users. Every user has one
+// role, the admin role always holding every permission and the user role
+// none.
+type Role struct {
+	common.Model
+	Name        string       `gorm:"type:varchar(64);not null;uniqueIndex:idx_roles_name,where:deleted_at IS NULL" json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+}
+
+var Permissions = []Permission{
+	{Name: PermissionFilmsManage, Description: "Manage films, genres, people and posters"},
+	{Name: PermissionAreasManage, Description: "Manage areas and their layouts"},
+	{Name: PermissionSessionsManage, Description: "Manage and schedule sessions"},
+	{Name: PermissionPricingManage, Description: "Manage price rules and promo codes"},
+	{Name: PermissionTransactionsRead, Description: "View every transaction"},
+	{Name: PermissionTransactionsManage, Description: "Confirm, cancel and delete transactions"},
+	{Name: PermissionTransactionsRefund, Description: "Refund transactions"},
+	{Name: PermissionUsersRead, Description: "View every user"},
+	{Name: PermissionUsersManage, Description: "Create, update and suspend users and reset their passwords"},
+	{Name: PermissionRolesManage, Description: "Manage roles and assign them to users"},
+}
+
+// DefaultRoles are created with their permissions when missing. Apart from
+// admin, their permissions can be changed afterwards.
+var DefaultRoles = map[string][]string{
+	RoleUser:          {},
+	RoleCashier:       {PermissionTransactionsRead, PermissionTransactionsManage},
+	RoleScheduler:     {PermissionSessionsManage, PermissionAreasManage},
+	RoleContentEditor: {PermissionFilmsManage},
+	RoleFinance:       {PermissionTransactionsRead, PermissionTransactionsRefund, PermissionPricingManage},
+}
+
+func IsPermission(name string) bool {
+	for _, permission := range Permissions {
+		if permission.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltInRole reports whether a role cannot be deleted nor its permissions
+// changed
+func IsBuiltInRole(name string) bool {
+	return name == RoleAdmin || name == RoleUser
+}
+
+func (r Role) PermissionNames() []string {
+	names := []string{}
+	for _, permission := range r.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
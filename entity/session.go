@@ -1,11 +1,17 @@
 package entity
 
-import "fp-rpl/common"
+import (
+	"fp-rpl/common"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Session struct {
 	common.Model
-	Time         string        `gorm:"type:time" json:"time" binding:"required"`
-	Price        float64       `json:"price" binding:"required"`
+	StartsAt     time.Time     `gorm:"type:timestamptz;index" json:"starts_at"`
+	EndsAt       time.Time     `gorm:"type:timestamptz" json:"ends_at"`
+	Price        common.Money  `gorm:"embedded;embeddedPrefix:price_" json:"price"`
 	Transactions []Transaction `json:"transaction,omitempty"`
 	Spots        []Spot        `json:"spot,omitempty"`
 	FilmID       uint64        `gorm:"foreignKey" json:"film_id" binding:"required"`
@@ -13,3 +19,14 @@ type Session struct {
 	AreaID       uint64        `gorm:"foreignKey" json:"area_id" binding:"required"`
 	Area         *Area         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"area,omitempty"`
 }
+
+// AfterFind shows the session times in the timezone of the cinema
+func (s *Session) AfterFind(tx *gorm.DB) error {
+	s.StartsAt = s.StartsAt.In(common.Location())
+	s.EndsAt = s.EndsAt.In(common.Location())
+	return nil
+}
+
+func (s *Session) HasStarted(now time.Time) bool {
+	return !s.StartsAt.After(now)
+}
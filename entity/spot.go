@@ -1,6 +1,17 @@
 package entity
 
-import "fp-rpl/common"
+import (
+	"fp-rpl/common"
+	"strconv"
+	"time"
+)
+
+const (
+	SpotRegular    = "regular"
+	SpotVIP        = "vip"
+	SpotCouple     = "couple"
+	SpotWheelchair = "wheelchair"
+)
 
 type Spot struct {
 	common.Model
@@ -8,6 +19,31 @@ type Spot struct {
 	Number        int          `json:"number" binding:"required"`
 	SessionID     uint64       `gorm:"foreignKey" json:"session_id" binding:"required"`
 	Session       *Session     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"session,omitempty"`
-	TransactionID *uint64      `gorm:"foreignKey" json:"transaction_id"`
+	TransactionID *uint64      `gorm:"foreignKey" json:"-"`
 	Transaction   *Transaction `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"transaction,omitempty"`
+	HeldByUserID  *uint64      `json:"-"`
+	HeldUntil     *time.Time   `gorm:"index" json:"held_until"`
+	IsBlocked     bool         `json:"is_blocked"`
+	Type          string       `json:"type"`
+	GridRow       int          `json:"grid_row"`
+	GridColumn    int          `json:"grid_column"`
+}
+
+func (s *Spot) Name() string {
+	return s.Row + strconv.Itoa(s.Number)
+}
+
+// IsHeld reports whether the spot is under a hold that has not expired yet
+func (s *Spot) IsHeld(now time.Time) bool {
+	return s.HeldByUserID != nil && s.HeldUntil != nil && s.HeldUntil.After(now)
+}
+
+// IsHeldBy reports whether the spot is under an unexpired hold of the given user
+func (s *Spot) IsHeldBy(userID uint64, now time.Time) bool {
+	return s.IsHeld(now) && *s.HeldByUserID == userID
+}
+
+func (s *Spot) ClearHold() {
+	s.HeldByUserID = nil
+	s.HeldUntil = nil
 }
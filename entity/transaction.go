@@ -1,14 +1,73 @@
 package entity
 
-import "fp-rpl/common"
+import (
+	"fp-rpl/common"
+	"time"
+)
+
+const (
+	TransactionPending        = "pending"
+	TransactionPaymentPending = "payment_pending"
+	TransactionPaid           = "paid"
+	TransactionCancelled      = "cancelled"
+	TransactionRefunded       = "refunded"
+	TransactionExpired        = "expired"
+)
+
+// transactionTransitions lists the statuses each status may move to. A
+// transaction is payment_pending while the payment gateway charges it.
+var transactionTransitions = map[string][]string{
+	TransactionPending:        {TransactionPaymentPending, TransactionPaid, TransactionCancelled, TransactionExpired},
+	TransactionPaymentPending: {TransactionPaid, TransactionPending},
+	TransactionPaid:           {TransactionCancelled, TransactionRefunded},
+}
 
 type Transaction struct {
 	common.Model
-	Code 	   string   `json:"code" binding:"required"`
-	TotalPrice float64  `json:"total_price" binding:"required"`
-	Spots      []Spot   `json:"spot,omitempty"`
-	UserID     uint64   `gorm:"foreignKey" json:"user_id" binding:"required"`
-	User       *User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"user,omitempty"`
-	SessionID  uint64   `gorm:"foreignKey" json:"session_id" binding:"required"`
-	Session    *Session `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"session,omitempty"`
+	Code               string            `json:"code" binding:"required"`
+	SubtotalPrice      common.Money      `gorm:"embedded;embeddedPrefix:subtotal_price_" json:"subtotal_price"`
+	DiscountPrice      common.Money      `gorm:"embedded;embeddedPrefix:discount_price_" json:"discount_price"`
+	TotalPrice         common.Money      `gorm:"embedded;embeddedPrefix:total_price_" json:"total_price"`
+	PromoCodeID        *uint64           `gorm:"foreignKey" json:"promo_code_id"`
+	PromoCode          *PromoCode        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"promo_code,omitempty"`
+	Status             string            `gorm:"index" json:"status"`
+	PaymentReference   string            `json:"payment_reference"`
+	PaymentKey         string            `gorm:"index" json:"-"`
+	ExpiresAt          *time.Time        `json:"expires_at"`
+	PaidAt             *time.Time        `json:"paid_at"`
+	CancelledAt        *time.Time        `json:"cancelled_at"`
+	CancellationReason string            `json:"cancellation_reason"`
+	RefundRequestedAt  *time.Time        `json:"refund_requested_at"`
+	RefundedAt         *time.Time        `json:"refunded_at"`
+	Spots              []Spot            `json:"spot,omitempty"`
+	Lines              []TransactionLine `json:"lines,omitempty"`
+	UserID             uint64            `gorm:"foreignKey" json:"user_id" binding:"required"`
+	User               *User             `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"user,omitempty"`
+	SessionID          uint64            `gorm:"foreignKey" json:"session_id" binding:"required"`
+	Session            *Session          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"session,omitempty"`
+}
+
+func (t *Transaction) CanTransitionTo(status string) bool {
+	for _, next := range transactionTransitions[t.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether the transaction can't change status anymore
+func (t *Transaction) IsFinal() bool {
+	return len(transactionTransitions[t.Status]) == 0
+}
+
+// RefundOwed reports whether the transaction was paid and then cancelled or
+// refunded, but the payment gateway has not given the money back yet
+func (t *Transaction) RefundOwed() bool {
+	return t.RefundRequestedAt != nil && t.RefundedAt == nil
+}
+
+// ReleasesSpots reports whether a transaction in the given status gives its spots back to the session
+func ReleasesSpots(status string) bool {
+	return status == TransactionCancelled || status == TransactionRefunded || status == TransactionExpired
 }
@@ -0,0 +1,21 @@
+package entity
+
+import "fp-rpl/common"
+
+// TransactionLine keeps the price of one booked spot as it was computed at
+// checkout, so later rule changes never alter historical totals
+type TransactionLine struct {
+	common.Model
+	TransactionID uint64            `gorm:"foreignKey" json:"transaction_id"`
+	SpotID        uint64            `json:"spot_id"`
+	SpotName      string            `json:"spot_name"`
+	SpotType      string            `json:"spot_type"`
+	BasePrice     common.Money      `gorm:"embedded;embeddedPrefix:base_price_" json:"base_price"`
+	Adjustments   []PriceAdjustment `gorm:"serializer:json" json:"adjustments"`
+	Price         common.Money      `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+}
+
+type PriceAdjustment struct {
+	Rule   string       `json:"rule"`
+	Amount common.Money `json:"amount"`
+}
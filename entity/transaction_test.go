@@ -0,0 +1,59 @@
+package entity
+
+import "testing"
+
+func TestTransactionCanTransitionTo(t *testing.T) {
+	statuses := []string{
+		TransactionPending,
+		TransactionPaymentPending,
+		TransactionPaid,
+		TransactionCancelled,
+		TransactionRefunded,
+		TransactionExpired,
+	}
+	allowed := map[string][]string{
+		TransactionPending:        {TransactionPaymentPending, TransactionPaid, TransactionCancelled, TransactionExpired},
+		TransactionPaymentPending: {TransactionPaid, TransactionPending},
+		TransactionPaid:           {TransactionCancelled, TransactionRefunded},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := false
+			for _, next := range allowed[from] {
+				if next == to {
+					want = true
+				}
+			}
+
+			t.Run(from+" to "+to, func(t *testing.T) {
+				transaction := Transaction{Status: from}
+				if got := transaction.CanTransitionTo(to); got != want {
+					t.Errorf("CanTransitionTo(%q) from %q = %v, want %v", to, from, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestTransactionIsFinal(t *testing.T) {
+	tests := []struct {
+		status string
+		final  bool
+	}{
+		{TransactionPending, false},
+		{TransactionPaymentPending, false},
+		{TransactionPaid, false},
+		{TransactionCancelled, true},
+		{TransactionRefunded, true},
+		{TransactionExpired, true},
+	}
+	for _, test := range tests {
+		t.Run(test.status, func(t *testing.T) {
+			transaction := Transaction{Status: test.status}
+			if got := transaction.IsFinal(); got != test.final {
+				t.Errorf("IsFinal() = %v, want %v", got, test.final)
+			}
+		})
+	}
+}
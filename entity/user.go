@@ -3,6 +3,7 @@ package entity
 import (
 	"fp-rpl/common"
 	"fp-rpl/utils"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -15,9 +16,27 @@ type User struct {
 	NoTelp       string        `json:"no_telp" binding:"required"`
 	Password     string        `json:"-" binding:"required"`
 	Role         string        `json:"role" binding:"required"`
+	BirthDate    *time.Time    `gorm:"type:date" json:"birth_date"`
+	SuspendedAt  *time.Time    `json:"suspended_at"`
 	Transactions []Transaction `json:"spot,omitempty"`
 }
 
+// AgeAt returns the age of the user at t in the cinema timezone. The user
+// must have a birth date.
+func (u User) AgeAt(t time.Time) int {
+	t = t.In(common.Location())
+	age := t.Year() - u.BirthDate.Year()
+	if t.Month() < u.BirthDate.Month() || (t.Month() == u.BirthDate.Month() && t.Day() < u.BirthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// IsSuspended reports whether the user has been stopped from logging in
+func (u User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	var err error
 	u.Password, err = utils.PasswordHash(u.Password)
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"fp-rpl/config"
 	"fp-rpl/controller"
 	"fp-rpl/middleware"
 	"fp-rpl/repository"
 	"fp-rpl/routes"
+	"fp-rpl/scheduler"
 	"fp-rpl/service"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -33,35 +36,111 @@ func main() {
 	sessionR := repository.NewSessionRepository(db)
 	spotR := repository.NewSpotRepository(db)
 	transactionR := repository.NewTransactionRepository(db)
+	priceRuleR := repository.NewPriceRuleRepository(db)
+	promoCodeR := repository.NewPromoCodeRepository(db)
+	genreR := repository.NewGenreRepository(db)
+	personR := repository.NewPersonRepository(db)
+	refreshTokenR := repository.NewRefreshTokenRepository(db)
+	roleR := repository.NewRoleRepository(db)
+	passwordResetTokenR := repository.NewPasswordResetTokenRepository(db)
 
 	// Setting Up Services
-	userS := service.NewUserService(userR)
-	filmS := service.NewFilmService(filmR)
-	jwtS := service.NewJWTService()
+	storage, err := service.NewStorage()
+	if err != nil {
+		fmt.Println(err)
+		panic(err)
+	}
+	mailer, err := service.NewMailer()
+	if err != nil {
+		fmt.Println(err)
+		panic(err)
+	}
+	userS := service.NewUserService(userR, roleR)
+	filmS := service.NewFilmService(filmR, genreR, personR, storage)
+	jwtS, err := service.NewJWTService()
+	if err != nil {
+		fmt.Println(err)
+		panic(err)
+	}
+	authS := service.NewAuthService(refreshTokenR, userR, roleR, jwtS)
 	areaS := service.NewAreaService(areaR)
-	sessionS := service.NewSessionService(sessionR, spotR)
-	spotS := service.NewSpotService(spotR)
-	transactionS := service.NewTransactionService(transactionR)
+	sessionS := service.NewSessionService(sessionR, spotR, areaR)
+	seatEventS := service.NewSeatEventService()
+	spotS := service.NewSpotService(spotR, seatEventS)
+	paymentG, err := service.NewPaymentGateway()
+	if err != nil {
+		fmt.Println(err)
+		panic(err)
+	}
+	transactionS := service.NewTransactionService(transactionR, spotR, sessionR, priceRuleR, promoCodeR, userR, paymentG, seatEventS)
+	priceRuleS := service.NewPriceRuleService(priceRuleR)
+	promoCodeS := service.NewPromoCodeService(promoCodeR)
+	genreS := service.NewGenreService(genreR, filmR)
+	personS := service.NewPersonService(personR, filmR)
+	roleS := service.NewRoleService(roleR, userR)
+	passwordResetS := service.NewPasswordResetService(passwordResetTokenR, userR, mailer)
 
 	// Setting Up Controllers
-	userC := controller.NewUserController(userS, jwtS)
+	userC := controller.NewUserController(userS, authS, passwordResetS)
 	filmC := controller.NewFilmController(filmS)
 	areaC := controller.NewAreaController(areaS)
-	sessionC := controller.NewSessionController(sessionS, areaS, filmS)
-	transactionC := controller.NewTransactionController(transactionS, sessionS, spotS, userS)
+	sessionC := controller.NewSessionController(sessionS, areaS, filmS, spotS, seatEventS)
+	transactionC := controller.NewTransactionController(transactionS, sessionS, userS)
+	priceRuleC := controller.NewPriceRuleController(priceRuleS)
+	promoCodeC := controller.NewPromoCodeController(promoCodeS)
+	genreC := controller.NewGenreController(genreS)
+	personC := controller.NewPersonController(personS)
+	mediaC := controller.NewMediaController(storage)
+	roleC := controller.NewRoleController(roleS, authS)
 
 	defer config.DBClose(db)
 
+	// Setting Up Schedulers
+	schedulerCtx, stopSchedulers := context.WithCancel(context.Background())
+	defer stopSchedulers()
+
+	scheduler.Every(schedulerCtx, time.Minute, "release expired spot holds", func(ctx context.Context) error {
+		_, err := spotS.ReleaseExpiredHolds(ctx)
+		return err
+	})
+	scheduler.Every(schedulerCtx, time.Minute, "expire pending transactions", func(ctx context.Context) error {
+		_, err := transactionS.ExpirePendingTransactions(ctx)
+		return err
+	})
+	scheduler.Every(schedulerCtx, time.Minute, "settle payments", func(ctx context.Context) error {
+		_, err := transactionS.SettlePayments(ctx)
+		return err
+	})
+	scheduler.Every(schedulerCtx, time.Minute, "update scheduled film statuses", func(ctx context.Context) error {
+		_, err := filmS.UpdateScheduledStatuses(ctx)
+		return err
+	})
+	scheduler.Every(schedulerCtx, time.Hour, "delete expired tokens", func(ctx context.Context) error {
+		_, err := authS.DeleteExpiredTokens(ctx)
+		return err
+	})
+	scheduler.Every(schedulerCtx, time.Hour, "delete expired password reset tokens", func(ctx context.Context) error {
+		_, err := passwordResetS.DeleteExpiredPasswordResetTokens(ctx)
+		return err
+	})
+
 	// Setting Up Server
 	server := gin.Default()
 	server.Use(middleware.CORSMiddleware())
 
 	// Setting Up Routes
-	routes.UserRoutes(server, userC)
-	routes.FilmRoutes(server, filmC)
-	routes.AreaRoutes(server, areaC)
-	routes.SessionRoutes(server, sessionC)
-	routes.TransactionRoutes(server, transactionC)
+	routes.UserRoutes(server, userC, authS)
+	routes.FilmRoutes(server, filmC, authS)
+	routes.AreaRoutes(server, areaC, authS)
+	routes.SessionRoutes(server, sessionC, authS)
+	routes.ShowtimeRoutes(server, sessionC)
+	routes.TransactionRoutes(server, transactionC, authS)
+	routes.PriceRuleRoutes(server, priceRuleC, authS)
+	routes.PromoCodeRoutes(server, promoCodeC, authS)
+	routes.GenreRoutes(server, genreC, authS)
+	routes.PersonRoutes(server, personC, authS)
+	routes.MediaRoutes(server, mediaC)
+	routes.RoleRoutes(server, roleC, authS)
 
 	// Running in localhost:8080
 	port := os.Getenv("PORT")
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"fmt"
 	"fp-rpl/common"
 	"fp-rpl/service"
 	"net/http"
@@ -10,7 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate(jwtService service.JWTService, role string) gin.HandlerFunc {
+// Authenticate lets through requests with a valid access token. With
+// permissions given, the token must also hold every one of them.
+func Authenticate(authService service.AuthService, permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -24,37 +25,24 @@ func Authenticate(jwtService service.JWTService, role string) gin.HandlerFunc {
 			return
 		}
 		authHeader = strings.Replace(authHeader, "Bearer ", "", -1)
-		token, err := jwtService.ValidateToken(authHeader)
+		claims, err := authService.ValidateAccessToken(c.Request.Context(), authHeader)
 		if err != nil {
 			response := common.CreateFailResponse("Invalid token", http.StatusUnauthorized)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
 			return
 		}
 
-		if !token.Valid {
-			response := common.CreateFailResponse("Invalid token", http.StatusUnauthorized)
-			c.AbortWithStatusJSON(http.StatusForbidden, response)
-			return
-		}
-
-		// get role from token
-		roleRes, err := jwtService.GetRoleByToken(string(authHeader))
-		fmt.Println("ROLE", roleRes)
-		if err != nil || (roleRes != "admin" && roleRes != role) {
-			response := common.CreateFailResponse("Action unauthorized", http.StatusUnauthorized)
-			c.AbortWithStatusJSON(http.StatusForbidden, response)
-			return
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				response := common.CreateFailResponse("Action unauthorized", http.StatusUnauthorized)
+				c.AbortWithStatusJSON(http.StatusForbidden, response)
+				return
+			}
 		}
 
-		// get userID from token
-		idRes, err := jwtService.GetIDByToken(authHeader)
-		if err != nil {
-			response := common.CreateFailResponse("Failed to process request", http.StatusUnauthorized)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
-			return
-		}
-		fmt.Println("ROLE", roleRes)
-		c.Set("ID", idRes)
+		c.Set("ID", claims.UserID)
+		c.Set("FamilyID", claims.FamilyID)
+		c.Set("Permissions", claims.Permissions)
 		c.Next()
 	}
 }
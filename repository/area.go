@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type areaRepository struct {
@@ -23,9 +25,10 @@ type AreaRepository interface {
 	// functional
 	GetAreaByName(ctx context.Context, tx *gorm.DB, name string) (entity.Area, error)
 	CreateNewArea(ctx context.Context, tx *gorm.DB, area entity.Area) (entity.Area, error)
-	GetAllAreas(ctx context.Context, tx *gorm.DB) ([]entity.Area, error)
+	GetAllAreas(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Area, int64, error)
 	GetAreaByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Area, error)
 	UpdateArea(ctx context.Context, tx *gorm.DB, areaDTO dto.AreaCreateRequest, area entity.Area) (entity.Area, error)
+	LockAreaByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Area, error)
 	DeleteAreaByID(ctx context.Context, tx *gorm.DB, id uint64) error
 }
 
@@ -43,7 +46,7 @@ func (areaR *areaRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
 
 func (areaR *areaRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -84,21 +87,23 @@ func (areaR *areaRepository) CreateNewArea(ctx context.Context, tx *gorm.DB, are
 	return area, nil
 }
 
-func (areaR *areaRepository) GetAllAreas(ctx context.Context, tx *gorm.DB) ([]entity.Area, error) {
-	var err error
+func (areaR *areaRepository) GetAllAreas(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Area, int64, error) {
 	var areas []entity.Area
-
+	var total int64
 	if tx == nil {
-		tx = areaR.db.WithContext(ctx).Debug().Find(&areas)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Find(&areas).Error
+		tx = areaR.db
 	}
 
+	err := tx.WithContext(ctx).Debug().Model(&entity.Area{}).Scopes(query.Filter).Count(&total).Error
+	if err != nil {
+		return areas, 0, err
+	}
+
+	err = tx.WithContext(ctx).Debug().Scopes(query.Paginate).Find(&areas).Error
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return areas, err
+		return areas, 0, err
 	}
-	return areas, nil
+	return areas, total, nil
 }
 
 func (areaR *areaRepository) GetAreaByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Area, error) {
@@ -149,3 +154,18 @@ func (areaR *areaRepository) DeleteAreaByID(ctx context.Context, tx *gorm.DB, id
 	}
 	return nil
 }
+
+// LockAreaByID takes the area with SELECT ... FOR UPDATE, so it must be
+// called with an open db transaction
+func (areaR *areaRepository) LockAreaByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Area, error) {
+	var area entity.Area
+	if tx == nil {
+		return area, errors.New("locking an area requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = $1", id).Take(&area).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return area, err
+	}
+	return area, nil
+}
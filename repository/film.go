@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type filmRepository struct {
@@ -22,13 +24,22 @@ type FilmRepository interface {
 
 	// functional
 	CreateNewFilm(ctx context.Context, tx *gorm.DB, user entity.Film) (entity.Film, error)
-	GetAllFilms(ctx context.Context, tx *gorm.DB) ([]entity.Film, error)
+	GetAllFilms(ctx context.Context, tx *gorm.DB, query common.Query, filter dto.FilmFilter) ([]entity.Film, int64, error)
 	GetFilmBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Film, error)
 	GetFilmByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Film, error)
 	GetFilmDetailBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Film, error)
 	UpdateFilmBySlug(ctx context.Context, tx *gorm.DB, filmDTO dto.FilmRegisterRequest, film entity.Film) (entity.Film, error)
 	DeleteFilm(ctx context.Context, tx *gorm.DB, slug string) error
 	GetAllFilmsByStatus(ctx context.Context, tx *gorm.DB,status string) ([]entity.Film, error)
+	SearchFilms(ctx context.Context, tx *gorm.DB, search string, query common.Query) ([]entity.Film, int64, error)
+	SearchFilmsBySimilarity(ctx context.Context, tx *gorm.DB, search string, query common.Query) ([]entity.Film, int64, error)
+	ReplaceFilmGenres(ctx context.Context, tx *gorm.DB, film entity.Film, genres []entity.Genre) error
+	ReplaceFilmCredits(ctx context.Context, tx *gorm.DB, film entity.Film, credits []entity.FilmCredit) error
+	RefreshFilmSearch(ctx context.Context, tx *gorm.DB, filmIDs []uint64) error
+	GetScheduledFilms(ctx context.Context, tx *gorm.DB) ([]entity.Film, error)
+	UpdateFilmStatus(ctx context.Context, tx *gorm.DB, id uint64, status string) error
+	UpdateFilmPoster(ctx context.Context, tx *gorm.DB, film entity.Film) (entity.Film, error)
+	UpdateFilmTrailer(ctx context.Context, tx *gorm.DB, film entity.Film) (entity.Film, error)
 }
 
 func NewFilmRepository(db *gorm.DB) FilmRepository {
@@ -44,7 +55,7 @@ func (filmR *filmRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
 
 func (filmR *filmRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -69,21 +80,46 @@ func (filmR *filmRepository) CreateNewFilm(ctx context.Context, tx *gorm.DB, fil
 	return film, nil
 }
 
-func (filmR *filmRepository) GetAllFilms(ctx context.Context, tx *gorm.DB) ([]entity.Film, error) {
-	var err error
-	var films []entity.Film
+// filmFilter narrows films down to those matching filter
+func filmFilter(filter dto.FilmFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Genre != "" {
+			db = db.Where("id IN (SELECT film_genres.film_id FROM film_genres JOIN genres ON genres.id = film_genres.genre_id WHERE genres.slug = ? AND genres.deleted_at IS NULL)", filter.Genre)
+		}
+		if filter.PersonID != 0 && filter.Role != "" {
+			db = db.Where("id IN (SELECT film_id FROM film_credits WHERE person_id = ? AND role = ?)", filter.PersonID, filter.Role)
+		} else if filter.PersonID != 0 {
+			db = db.Where("id IN (SELECT film_id FROM film_credits WHERE person_id = ?)", filter.PersonID)
+		}
+		return db
+	}
+}
+
+// filmCredits preloads the credits of films with the people in them, in
+// billing order
+func filmCredits(db *gorm.DB) *gorm.DB {
+	return db.Preload("Credits", func(db *gorm.DB) *gorm.DB {
+		return db.Where("person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").Order("role").Order("billing")
+	}).Preload("Credits.Person")
+}
 
+func (filmR *filmRepository) GetAllFilms(ctx context.Context, tx *gorm.DB, query common.Query, filter dto.FilmFilter) ([]entity.Film, int64, error) {
+	var films []entity.Film
+	var total int64
 	if tx == nil {
-		tx = filmR.db.WithContext(ctx).Debug().Find(&films)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Find(&films).Error
+		tx = filmR.db
 	}
 
+	err := tx.WithContext(ctx).Debug().Model(&entity.Film{}).Scopes(query.Filter, filmFilter(filter)).Count(&total).Error
+	if err != nil {
+		return films, 0, err
+	}
+
+	err = tx.WithContext(ctx).Debug().Scopes(filmFilter(filter), query.Paginate).Preload("Genres").Find(&films).Error
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return films, err
+		return films, 0, err
 	}
-	return films, nil
+	return films, total, nil
 }
 func (filmR *filmRepository) GetAllFilmsByStatus(ctx context.Context, tx *gorm.DB,status string) ([]entity.Film, error) {
 	var err error
@@ -137,10 +173,10 @@ func (filmR *filmRepository) GetFilmDetailBySlug(ctx context.Context, tx *gorm.D
 	var err error
 	var film entity.Film
 	if tx == nil {
-		tx = filmR.db.WithContext(ctx).Debug().Where("slug = $1", slug).Preload("Sessions").Take(&film)
+		tx = filmR.db.WithContext(ctx).Debug().Where("slug = $1", slug).Preload("Sessions").Preload("Genres").Scopes(filmCredits).Take(&film)
 		err = tx.Error
 	} else {
-		err = tx.WithContext(ctx).Debug().Where("slug = $1", slug).Preload("Sessions").Take(&film).Error
+		err = tx.WithContext(ctx).Debug().Where("slug = $1", slug).Preload("Sessions").Preload("Genres").Scopes(filmCredits).Take(&film).Error
 	}
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
@@ -158,7 +194,7 @@ func (filmR *filmRepository) UpdateFilmBySlug(ctx context.Context, tx *gorm.DB,
 		tx = filmR.db.WithContext(ctx).Debug()
 	}
 
-	tx = tx.Save(&filmUpdate)
+	tx = tx.Omit(clause.Associations).Save(&filmUpdate)
 	err = tx.Error
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
@@ -181,3 +217,183 @@ func (filmR *filmRepository) DeleteFilm(ctx context.Context, tx *gorm.DB, slug s
 	}
 	return nil
 }
+
+// searchableFilmStatuses are the statuses of films visitors can see, the
+// others are left out of search results
+var searchableFilmStatuses = []string{entity.FilmNowPlaying, entity.FilmComingSoon}
+
+// searchableCreditRoles are the credits whose names films can be searched by
+var searchableCreditRoles = []string{entity.CreditCast, entity.CreditDirector}
+
+// SearchFilms runs a full-text search over the title, genres, director, cast
+// and synopsis of films, best matches first
+func (filmR *filmRepository) SearchFilms(ctx context.Context, tx *gorm.DB, search string, query common.Query) ([]entity.Film, int64, error) {
+	var films []entity.Film
+	var total int64
+	if tx == nil {
+		tx = filmR.db
+	}
+
+	match := "status IN ? AND search_vector @@ websearch_to_tsquery('simple', ?)"
+	err := tx.WithContext(ctx).Debug().Model(&entity.Film{}).Where(match, searchableFilmStatuses, search).Count(&total).Error
+	if err != nil {
+		return films, 0, err
+	}
+
+	rank := clause.OrderBy{Expression: clause.Expr{SQL: "ts_rank(search_vector, websearch_to_tsquery('simple', ?)) DESC, id", Vars: []any{search}}}
+	err = tx.WithContext(ctx).Debug().Where(match, searchableFilmStatuses, search).Clauses(rank).Scopes(query.Paginate).Preload("Genres").Find(&films).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return films, 0, err
+	}
+	return films, total, nil
+}
+
+// SearchFilmsBySimilarity finds films whose title, director or cast resemble
+// the search, which still matches when it is misspelled
+func (filmR *filmRepository) SearchFilmsBySimilarity(ctx context.Context, tx *gorm.DB, search string, query common.Query) ([]entity.Film, int64, error) {
+	var films []entity.Film
+	var total int64
+	if tx == nil {
+		tx = filmR.db
+	}
+
+	people := "FROM film_credits JOIN people ON people.id = film_credits.person_id WHERE film_credits.role IN ? AND people.deleted_at IS NULL"
+	match := "status IN ? AND (? <% title OR id IN (SELECT film_credits.film_id " + people + " AND ? <% people.name))"
+	err := tx.WithContext(ctx).Debug().Model(&entity.Film{}).Where(match, searchableFilmStatuses, search, searchableCreditRoles, search).Count(&total).Error
+	if err != nil {
+		return films, 0, err
+	}
+
+	rank := clause.OrderBy{Expression: clause.Expr{
+		SQL:  "GREATEST(word_similarity(?, title), COALESCE((SELECT MAX(word_similarity(?, people.name)) " + people + " AND film_credits.film_id = films.id), 0)) DESC, id",
+		Vars: []any{search, search, searchableCreditRoles},
+	}}
+	err = tx.WithContext(ctx).Debug().Where(match, searchableFilmStatuses, search, searchableCreditRoles, search).Clauses(rank).Scopes(query.Paginate).Preload("Genres").Find(&films).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return films, 0, err
+	}
+	return films, total, nil
+}
+
+// ReplaceFilmGenres sets the genres of a film to exactly genres
+func (filmR *filmRepository) ReplaceFilmGenres(ctx context.Context, tx *gorm.DB, film entity.Film, genres []entity.Genre) error {
+	if tx == nil {
+		tx = filmR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&film).Omit("Genres.*").Association("Genres").Replace(genres)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReplaceFilmCredits sets the credits of a film to exactly credits
+func (filmR *filmRepository) ReplaceFilmCredits(ctx context.Context, tx *gorm.DB, film entity.Film, credits []entity.FilmCredit) error {
+	if tx == nil {
+		tx = filmR.db
+	}
+
+	return tx.WithContext(ctx).Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("film_id = ?", film.ID).Delete(&entity.FilmCredit{}).Error
+		if err != nil || len(credits) == 0 {
+			return err
+		}
+
+		for i := range credits {
+			credits[i].FilmID = film.ID
+		}
+		return tx.Omit(clause.Associations).Create(&credits).Error
+	})
+}
+
+// RefreshFilmSearch rebuilds the weighted full-text search vector of films,
+// which must happen whenever their title, synopsis, genres or credits change
+func (filmR *filmRepository) RefreshFilmSearch(ctx context.Context, tx *gorm.DB, filmIDs []uint64) error {
+	var err error
+	if len(filmIDs) == 0 {
+		return nil
+	}
+
+	vector := `setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce((SELECT string_agg(genres.name, ' ') FROM film_genres JOIN genres ON genres.id = film_genres.genre_id WHERE film_genres.film_id = films.id AND genres.deleted_at IS NULL), '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce((SELECT string_agg(people.name, ' ') FROM film_credits JOIN people ON people.id = film_credits.person_id WHERE film_credits.film_id = films.id AND film_credits.role IN ? AND people.deleted_at IS NULL), '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(synopsis, '')), 'C')`
+	if tx == nil {
+		tx = filmR.db.WithContext(ctx).Debug().Exec("UPDATE films SET search_vector = "+vector+" WHERE id IN ?", searchableCreditRoles, filmIDs)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Exec("UPDATE films SET search_vector = "+vector+" WHERE id IN ?", searchableCreditRoles, filmIDs).Error
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetScheduledFilms returns the films whose status follows their release and
+// end dates
+func (filmR *filmRepository) GetScheduledFilms(ctx context.Context, tx *gorm.DB) ([]entity.Film, error) {
+	var err error
+	var films []entity.Film
+
+	if tx == nil {
+		tx = filmR.db.WithContext(ctx).Debug().Where("release_date IS NOT NULL AND NOT status_override").Find(&films)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("release_date IS NOT NULL AND NOT status_override").Find(&films).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return films, err
+	}
+	return films, nil
+}
+
+func (filmR *filmRepository) UpdateFilmStatus(ctx context.Context, tx *gorm.DB, id uint64, status string) error {
+	var err error
+	if tx == nil {
+		tx = filmR.db.WithContext(ctx).Debug().Model(&entity.Film{}).Where("id = ?", id).Update("status", status)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&entity.Film{}).Where("id = ?", id).Update("status", status).Error
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateFilmPoster saves the image, poster key and thumbnails of a film
+func (filmR *filmRepository) UpdateFilmPoster(ctx context.Context, tx *gorm.DB, film entity.Film) (entity.Film, error) {
+	var err error
+	if tx == nil {
+		tx = filmR.db.WithContext(ctx).Debug().Model(&film).Select("image", "poster_key", "thumbnails").Updates(&film)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&film).Select("image", "poster_key", "thumbnails").Updates(&film).Error
+	}
+
+	if err != nil {
+		return film, err
+	}
+	return film, nil
+}
+
+// UpdateFilmTrailer saves the trailer and trailer key of a film
+func (filmR *filmRepository) UpdateFilmTrailer(ctx context.Context, tx *gorm.DB, film entity.Film) (entity.Film, error) {
+	var err error
+	if tx == nil {
+		tx = filmR.db.WithContext(ctx).Debug().Model(&film).Select("trailer", "trailer_key").Updates(&film)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&film).Select("trailer", "trailer_key").Updates(&film).Error
+	}
+
+	if err != nil {
+		return film, err
+	}
+	return film, nil
+}
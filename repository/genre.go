@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/entity"
+
+	"gorm.io/gorm"
+)
+
+type genreRepository struct {
+	db *gorm.DB
+}
+
+type GenreRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateNewGenre(ctx context.Context, tx *gorm.DB, genre entity.Genre) (entity.Genre, error)
+	GetAllGenres(ctx context.Context, tx *gorm.DB) ([]entity.Genre, error)
+	GetGenreByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Genre, error)
+	GetGenreBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Genre, error)
+	GetGenresByIDs(ctx context.Context, tx *gorm.DB, ids []uint64) ([]entity.Genre, error)
+	GetFilmIDsByGenreID(ctx context.Context, tx *gorm.DB, id uint64) ([]uint64, error)
+	UpdateGenre(ctx context.Context, tx *gorm.DB, genre entity.Genre) (entity.Genre, error)
+	DeleteGenreByID(ctx context.Context, tx *gorm.DB, id uint64) error
+}
+
+func NewGenreRepository(db *gorm.DB) *genreRepository {
+	return &genreRepository{db: db}
+}
+
+func (genreR *genreRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := genreR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (genreR *genreRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (genreR *genreRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (genreR *genreRepository) CreateNewGenre(ctx context.Context, tx *gorm.DB, genre entity.Genre) (entity.Genre, error) {
+	var err error
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Create(&genre)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Create(&genre).Error
+	}
+
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	return genre, nil
+}
+
+func (genreR *genreRepository) GetAllGenres(ctx context.Context, tx *gorm.DB) ([]entity.Genre, error) {
+	var err error
+	var genres []entity.Genre
+
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Order("name").Find(&genres)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Order("name").Find(&genres).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return genres, err
+	}
+	return genres, nil
+}
+
+func (genreR *genreRepository) GetGenreByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Genre, error) {
+	var err error
+	var genre entity.Genre
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Where("id = ?", id).Take(&genre)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = ?", id).Take(&genre).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return genre, err
+	}
+	return genre, nil
+}
+
+func (genreR *genreRepository) GetGenreBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Genre, error) {
+	var err error
+	var genre entity.Genre
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Where("slug = ?", slug).Take(&genre)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("slug = ?", slug).Take(&genre).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return genre, err
+	}
+	return genre, nil
+}
+
+func (genreR *genreRepository) GetGenresByIDs(ctx context.Context, tx *gorm.DB, ids []uint64) ([]entity.Genre, error) {
+	var err error
+	var genres []entity.Genre
+	if len(ids) == 0 {
+		return genres, nil
+	}
+
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Where("id IN ?", ids).Find(&genres)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id IN ?", ids).Find(&genres).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return genres, err
+	}
+	return genres, nil
+}
+
+func (genreR *genreRepository) GetFilmIDsByGenreID(ctx context.Context, tx *gorm.DB, id uint64) ([]uint64, error) {
+	var err error
+	var filmIDs []uint64
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Table("film_genres").Where("genre_id = ?", id).Pluck("film_id", &filmIDs)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Table("film_genres").Where("genre_id = ?", id).Pluck("film_id", &filmIDs).Error
+	}
+
+	if err != nil {
+		return filmIDs, err
+	}
+	return filmIDs, nil
+}
+
+func (genreR *genreRepository) UpdateGenre(ctx context.Context, tx *gorm.DB, genre entity.Genre) (entity.Genre, error) {
+	var err error
+	if tx == nil {
+		tx = genreR.db.WithContext(ctx).Debug().Omit("Films").Save(&genre)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit("Films").Save(&genre).Error
+	}
+
+	if err != nil {
+		return genre, err
+	}
+	return genre, nil
+}
+
+// DeleteGenreByID deletes the genre and takes it off every film
+func (genreR *genreRepository) DeleteGenreByID(ctx context.Context, tx *gorm.DB, id uint64) error {
+	if tx == nil {
+		tx = genreR.db
+	}
+
+	return tx.WithContext(ctx).Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM film_genres WHERE genre_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Genre{}, id).Error
+	})
+}
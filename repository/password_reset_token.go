@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+type PasswordResetTokenRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreatePasswordResetToken(ctx context.Context, tx *gorm.DB, resetToken entity.PasswordResetToken) (entity.PasswordResetToken, error)
+	LockPasswordResetTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error)
+	UsePasswordResetTokensOfUser(ctx context.Context, tx *gorm.DB, userID uint64, usedAt time.Time) error
+	DeleteExpiredPasswordResetTokens(ctx context.Context, tx *gorm.DB, now time.Time) (int64, error)
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) *passwordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (resetTokenR *passwordResetTokenRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := resetTokenR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (resetTokenR *passwordResetTokenRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (resetTokenR *passwordResetTokenRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (resetTokenR *passwordResetTokenRepository) CreatePasswordResetToken(ctx context.Context, tx *gorm.DB, resetToken entity.PasswordResetToken) (entity.PasswordResetToken, error) {
+	if tx == nil {
+		tx = resetTokenR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Create(&resetToken).Error
+	if err != nil {
+		return entity.PasswordResetToken{}, err
+	}
+	return resetToken, nil
+}
+
+// LockPasswordResetTokenByHash takes the reset token with SELECT ... FOR
+// UPDATE, so it must be called with an open db transaction
+func (resetTokenR *passwordResetTokenRepository) LockPasswordResetTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error) {
+	var resetToken entity.PasswordResetToken
+	if tx == nil {
+		return resetToken, errors.New("locking a password reset token requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).Take(&resetToken).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return resetToken, err
+	}
+	return resetToken, nil
+}
+
+// UsePasswordResetTokensOfUser voids every reset token of a user that hasn't
+// been used yet
+func (resetTokenR *passwordResetTokenRepository) UsePasswordResetTokensOfUser(ctx context.Context, tx *gorm.DB, userID uint64, usedAt time.Time) error {
+	if tx == nil {
+		tx = resetTokenR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", usedAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredPasswordResetTokens removes reset tokens that have expired or
+// been used, neither of which can be used anymore
+func (resetTokenR *passwordResetTokenRepository) DeleteExpiredPasswordResetTokens(ctx context.Context, tx *gorm.DB, now time.Time) (int64, error) {
+	if tx == nil {
+		tx = resetTokenR.db
+	}
+
+	res := tx.WithContext(ctx).Debug().Unscoped().Where("expires_at < ? OR used_at IS NOT NULL", now).Delete(&entity.PasswordResetToken{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/common"
+	"fp-rpl/entity"
+
+	"gorm.io/gorm"
+)
+
+type personRepository struct {
+	db *gorm.DB
+}
+
+type PersonRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateNewPerson(ctx context.Context, tx *gorm.DB, person entity.Person) (entity.Person, error)
+	GetAllPeople(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Person, int64, error)
+	GetPersonByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Person, error)
+	GetPersonDetailByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Person, error)
+	GetPersonBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Person, error)
+	GetPeopleByIDs(ctx context.Context, tx *gorm.DB, ids []uint64) ([]entity.Person, error)
+	GetFilmIDsByPersonID(ctx context.Context, tx *gorm.DB, id uint64) ([]uint64, error)
+	UpdatePerson(ctx context.Context, tx *gorm.DB, person entity.Person) (entity.Person, error)
+	DeletePersonByID(ctx context.Context, tx *gorm.DB, id uint64) error
+}
+
+func NewPersonRepository(db *gorm.DB) *personRepository {
+	return &personRepository{db: db}
+}
+
+func (personR *personRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := personR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (personR *personRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (personR *personRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (personR *personRepository) CreateNewPerson(ctx context.Context, tx *gorm.DB, person entity.Person) (entity.Person, error) {
+	var err error
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Create(&person)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Create(&person).Error
+	}
+
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return person, nil
+}
+
+func (personR *personRepository) GetAllPeople(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Person, int64, error) {
+	var people []entity.Person
+	var total int64
+	if tx == nil {
+		tx = personR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.Person{}).Scopes(query.Filter).Count(&total).Error
+	if err != nil {
+		return people, 0, err
+	}
+
+	err = tx.WithContext(ctx).Debug().Scopes(query.Paginate).Find(&people).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return people, 0, err
+	}
+	return people, total, nil
+}
+
+func (personR *personRepository) GetPersonByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Person, error) {
+	var err error
+	var person entity.Person
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Where("id = ?", id).Take(&person)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = ?", id).Take(&person).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return person, err
+	}
+	return person, nil
+}
+
+// GetPersonDetailByID also loads the films the person is credited in
+func (personR *personRepository) GetPersonDetailByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Person, error) {
+	var err error
+	var person entity.Person
+	credits := func(db *gorm.DB) *gorm.DB {
+		return db.Where("film_id IN (SELECT id FROM films WHERE deleted_at IS NULL)").Order("role").Order("film_id")
+	}
+
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Where("id = ?", id).Preload("Credits", credits).Preload("Credits.Film").Take(&person)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = ?", id).Preload("Credits", credits).Preload("Credits.Film").Take(&person).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return person, err
+	}
+	return person, nil
+}
+
+func (personR *personRepository) GetPersonBySlug(ctx context.Context, tx *gorm.DB, slug string) (entity.Person, error) {
+	var err error
+	var person entity.Person
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Where("slug = ?", slug).Take(&person)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("slug = ?", slug).Take(&person).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return person, err
+	}
+	return person, nil
+}
+
+func (personR *personRepository) GetPeopleByIDs(ctx context.Context, tx *gorm.DB, ids []uint64) ([]entity.Person, error) {
+	var err error
+	var people []entity.Person
+	if len(ids) == 0 {
+		return people, nil
+	}
+
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Where("id IN ?", ids).Find(&people)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id IN ?", ids).Find(&people).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return people, err
+	}
+	return people, nil
+}
+
+func (personR *personRepository) GetFilmIDsByPersonID(ctx context.Context, tx *gorm.DB, id uint64) ([]uint64, error) {
+	var err error
+	var filmIDs []uint64
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Model(&entity.FilmCredit{}).Distinct("film_id").Where("person_id = ?", id).Pluck("film_id", &filmIDs)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&entity.FilmCredit{}).Distinct("film_id").Where("person_id = ?", id).Pluck("film_id", &filmIDs).Error
+	}
+
+	if err != nil {
+		return filmIDs, err
+	}
+	return filmIDs, nil
+}
+
+func (personR *personRepository) UpdatePerson(ctx context.Context, tx *gorm.DB, person entity.Person) (entity.Person, error) {
+	var err error
+	if tx == nil {
+		tx = personR.db.WithContext(ctx).Debug().Omit("Credits").Save(&person)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit("Credits").Save(&person).Error
+	}
+
+	if err != nil {
+		return person, err
+	}
+	return person, nil
+}
+
+// DeletePersonByID deletes the person along with their film credits
+func (personR *personRepository) DeletePersonByID(ctx context.Context, tx *gorm.DB, id uint64) error {
+	if tx == nil {
+		tx = personR.db
+	}
+
+	return tx.WithContext(ctx).Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("person_id = ?", id).Delete(&entity.FilmCredit{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Person{}, id).Error
+	})
+}
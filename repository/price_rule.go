@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+type priceRuleRepository struct {
+	db *gorm.DB
+}
+
+type PriceRuleRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateNewPriceRule(ctx context.Context, tx *gorm.DB, priceRule entity.PriceRule) (entity.PriceRule, error)
+	GetAllPriceRules(ctx context.Context, tx *gorm.DB) ([]entity.PriceRule, error)
+	GetPriceRuleByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.PriceRule, error)
+	UpdatePriceRule(ctx context.Context, tx *gorm.DB, priceRuleDTO dto.PriceRuleCreateRequest, priceRule entity.PriceRule) (entity.PriceRule, error)
+	DeletePriceRuleByID(ctx context.Context, tx *gorm.DB, id uint64) error
+}
+
+func NewPriceRuleRepository(db *gorm.DB) *priceRuleRepository {
+	return &priceRuleRepository{db: db}
+}
+
+func (priceRuleR *priceRuleRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := priceRuleR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (priceRuleR *priceRuleRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (priceRuleR *priceRuleRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (priceRuleR *priceRuleRepository) CreateNewPriceRule(ctx context.Context, tx *gorm.DB, priceRule entity.PriceRule) (entity.PriceRule, error) {
+	var err error
+	if tx == nil {
+		tx = priceRuleR.db.WithContext(ctx).Debug().Create(&priceRule)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Create(&priceRule).Error
+	}
+
+	if err != nil {
+		return entity.PriceRule{}, err
+	}
+	return priceRule, nil
+}
+
+func (priceRuleR *priceRuleRepository) GetAllPriceRules(ctx context.Context, tx *gorm.DB) ([]entity.PriceRule, error) {
+	var err error
+	var priceRules []entity.PriceRule
+
+	if tx == nil {
+		tx = priceRuleR.db.WithContext(ctx).Debug().Order("id").Find(&priceRules)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Order("id").Find(&priceRules).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return priceRules, err
+	}
+	return priceRules, nil
+}
+
+func (priceRuleR *priceRuleRepository) GetPriceRuleByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.PriceRule, error) {
+	var err error
+	var priceRule entity.PriceRule
+	if tx == nil {
+		tx = priceRuleR.db.WithContext(ctx).Debug().Where("id = $1", id).Take(&priceRule)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Take(&priceRule).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return priceRule, err
+	}
+	return priceRule, nil
+}
+
+func (priceRuleR *priceRuleRepository) UpdatePriceRule(ctx context.Context, tx *gorm.DB, priceRuleDTO dto.PriceRuleCreateRequest, priceRule entity.PriceRule) (entity.PriceRule, error) {
+	var err error
+	priceRuleUpdate := priceRule
+	copier.Copy(&priceRuleUpdate, &priceRuleDTO)
+
+	if tx == nil {
+		tx = priceRuleR.db.WithContext(ctx).Debug().Save(&priceRuleUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&priceRuleUpdate).Error
+	}
+
+	if err != nil {
+		return priceRuleUpdate, err
+	}
+	return priceRuleUpdate, nil
+}
+
+func (priceRuleR *priceRuleRepository) DeletePriceRuleByID(ctx context.Context, tx *gorm.DB, id uint64) error {
+	var err error
+	if tx == nil {
+		tx = priceRuleR.db.WithContext(ctx).Debug().Delete(&entity.PriceRule{}, id)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Delete(&entity.PriceRule{}, id).Error
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type promoCodeRepository struct {
+	db *gorm.DB
+}
+
+type PromoCodeRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateNewPromoCode(ctx context.Context, tx *gorm.DB, promoCode entity.PromoCode) (entity.PromoCode, error)
+	GetAllPromoCodes(ctx context.Context, tx *gorm.DB) ([]entity.PromoCode, error)
+	GetPromoCodeByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.PromoCode, error)
+	UpdatePromoCode(ctx context.Context, tx *gorm.DB, promoCodeDTO dto.PromoCodeCreateRequest, promoCode entity.PromoCode) (entity.PromoCode, error)
+	DeletePromoCodeByID(ctx context.Context, tx *gorm.DB, id uint64) error
+	GetPromoCodeByCode(ctx context.Context, tx *gorm.DB, code string) (entity.PromoCode, error)
+	LockPromoCodeByCode(ctx context.Context, tx *gorm.DB, code string) (entity.PromoCode, error)
+	AddPromoCodeUses(ctx context.Context, tx *gorm.DB, id uint64, uses int) error
+}
+
+func NewPromoCodeRepository(db *gorm.DB) *promoCodeRepository {
+	return &promoCodeRepository{db: db}
+}
+
+func (promoCodeR *promoCodeRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := promoCodeR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (promoCodeR *promoCodeRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (promoCodeR *promoCodeRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (promoCodeR *promoCodeRepository) CreateNewPromoCode(ctx context.Context, tx *gorm.DB, promoCode entity.PromoCode) (entity.PromoCode, error) {
+	var err error
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Create(&promoCode)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Create(&promoCode).Error
+	}
+
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+	return promoCode, nil
+}
+
+func (promoCodeR *promoCodeRepository) GetAllPromoCodes(ctx context.Context, tx *gorm.DB) ([]entity.PromoCode, error) {
+	var err error
+	var promoCodes []entity.PromoCode
+
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Order("id").Find(&promoCodes)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Order("id").Find(&promoCodes).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return promoCodes, err
+	}
+	return promoCodes, nil
+}
+
+func (promoCodeR *promoCodeRepository) GetPromoCodeByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.PromoCode, error) {
+	var err error
+	var promoCode entity.PromoCode
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Where("id = $1", id).Take(&promoCode)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Take(&promoCode).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return promoCode, err
+	}
+	return promoCode, nil
+}
+
+func (promoCodeR *promoCodeRepository) UpdatePromoCode(ctx context.Context, tx *gorm.DB, promoCodeDTO dto.PromoCodeCreateRequest, promoCode entity.PromoCode) (entity.PromoCode, error) {
+	var err error
+	promoCodeUpdate := promoCode
+	copier.Copy(&promoCodeUpdate, &promoCodeDTO)
+
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Omit("used_count").Save(&promoCodeUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit("used_count").Save(&promoCodeUpdate).Error
+	}
+
+	if err != nil {
+		return promoCodeUpdate, err
+	}
+	return promoCodeUpdate, nil
+}
+
+func (promoCodeR *promoCodeRepository) DeletePromoCodeByID(ctx context.Context, tx *gorm.DB, id uint64) error {
+	var err error
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Delete(&entity.PromoCode{}, id)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Delete(&entity.PromoCode{}, id).Error
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (promoCodeR *promoCodeRepository) GetPromoCodeByCode(ctx context.Context, tx *gorm.DB, code string) (entity.PromoCode, error) {
+	var err error
+	var promoCode entity.PromoCode
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Where("code = $1", code).Take(&promoCode)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("code = $1", code).Take(&promoCode).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return promoCode, err
+	}
+	return promoCode, nil
+}
+
+// LockPromoCodeByCode takes the promo code with SELECT ... FOR UPDATE,
+// so it must be called with an open db transaction
+func (promoCodeR *promoCodeRepository) LockPromoCodeByCode(ctx context.Context, tx *gorm.DB, code string) (entity.PromoCode, error) {
+	var promoCode entity.PromoCode
+	if tx == nil {
+		return promoCode, errors.New("locking a promo code requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = $1", code).Take(&promoCode).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return promoCode, err
+	}
+	return promoCode, nil
+}
+
+// AddPromoCodeUses moves the usage counter of a promo code by uses, never below zero
+func (promoCodeR *promoCodeRepository) AddPromoCodeUses(ctx context.Context, tx *gorm.DB, id uint64, uses int) error {
+	var err error
+	expr := gorm.Expr("GREATEST(used_count + ?, 0)", uses)
+	if tx == nil {
+		tx = promoCodeR.db.WithContext(ctx).Debug().Model(&entity.PromoCode{}).Where("id = ?", id).UpdateColumn("used_count", expr)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&entity.PromoCode{}).Where("id = ?", id).UpdateColumn("used_count", expr).Error
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+type RefreshTokenRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateRefreshToken(ctx context.Context, tx *gorm.DB, refreshToken entity.RefreshToken) (entity.RefreshToken, error)
+	LockRefreshTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (entity.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, tx *gorm.DB, id uint64, usedAt time.Time) error
+	GetActiveTokenFamilies(ctx context.Context, tx *gorm.DB, userID uint64, now time.Time, issuedAfter time.Time) ([]string, error)
+	RevokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, familyID string, revokedAt time.Time) error
+	CreateRevokedToken(ctx context.Context, tx *gorm.DB, revokedToken entity.RevokedToken) error
+	IsTokenFamilyRevoked(ctx context.Context, tx *gorm.DB, familyID string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context, tx *gorm.DB, now time.Time) (int64, error)
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *refreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (refreshTokenR *refreshTokenRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := refreshTokenR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (refreshTokenR *refreshTokenRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (refreshTokenR *refreshTokenRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+func (refreshTokenR *refreshTokenRepository) CreateRefreshToken(ctx context.Context, tx *gorm.DB, refreshToken entity.RefreshToken) (entity.RefreshToken, error) {
+	var err error
+	if tx == nil {
+		tx = refreshTokenR.db.WithContext(ctx).Debug().Create(&refreshToken)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Create(&refreshToken).Error
+	}
+
+	if err != nil {
+		return entity.RefreshToken{}, err
+	}
+	return refreshToken, nil
+}
+
+// LockRefreshTokenByHash takes the refresh token with SELECT ... FOR UPDATE,
+// so it must be called with an open db transaction
+func (refreshTokenR *refreshTokenRepository) LockRefreshTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (entity.RefreshToken, error) {
+	var refreshToken entity.RefreshToken
+	if tx == nil {
+		return refreshToken, errors.New("locking a refresh token requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).Take(&refreshToken).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return refreshToken, err
+	}
+	return refreshToken, nil
+}
+
+func (refreshTokenR *refreshTokenRepository) MarkRefreshTokenUsed(ctx context.Context, tx *gorm.DB, id uint64, usedAt time.Time) error {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.RefreshToken{}).Where("id = ?", id).Update("used_at", usedAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetActiveTokenFamilies lists the families of a user that can still be
+// refreshed, one for each device the user is logged in on, along with those
+// given a refresh token after issuedAfter, whose access token may still be
+// valid though the refresh token has expired
+func (refreshTokenR *refreshTokenRepository) GetActiveTokenFamilies(ctx context.Context, tx *gorm.DB, userID uint64, now time.Time, issuedAfter time.Time) ([]string, error) {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	var familyIDs []string
+	err := tx.WithContext(ctx).Debug().Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at > ? OR created_at > ?)", userID, now, issuedAfter).
+		Distinct().Pluck("family_id", &familyIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return familyIDs, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of a family that
+// isn't revoked yet
+func (refreshTokenR *refreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, familyID string, revokedAt time.Time) error {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateRevokedToken adds a family to the revocation list, keeping the later
+// expiry when it is listed already
+func (refreshTokenR *refreshTokenRepository) CreateRevokedToken(ctx context.Context, tx *gorm.DB, revokedToken entity.RevokedToken) error {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "family_id"}},
+		DoUpdates: clause.Set{{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)")}},
+	}).Create(&revokedToken).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (refreshTokenR *refreshTokenRepository) IsTokenFamilyRevoked(ctx context.Context, tx *gorm.DB, familyID string) (bool, error) {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	var count int64
+	err := tx.WithContext(ctx).Debug().Model(&entity.RevokedToken{}).Where("family_id = ?", familyID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredTokens removes expired refresh tokens and revocation list
+// entries, neither of which can be used anymore
+func (refreshTokenR *refreshTokenRepository) DeleteExpiredTokens(ctx context.Context, tx *gorm.DB, now time.Time) (int64, error) {
+	if tx == nil {
+		tx = refreshTokenR.db
+	}
+
+	res := tx.WithContext(ctx).Debug().Unscoped().Where("expires_at < ?", now).Delete(&entity.RefreshToken{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	deleted := res.RowsAffected
+
+	res = tx.WithContext(ctx).Debug().Where("expires_at < ?", now).Delete(&entity.RevokedToken{})
+	if res.Error != nil {
+		return deleted, res.Error
+	}
+	return deleted + res.RowsAffected, nil
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/entity"
+
+	"gorm.io/gorm"
+)
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+type RoleRepository interface {
+	// db transaction
+	BeginTx(ctx context.Context) (*gorm.DB, error)
+	CommitTx(ctx context.Context, tx *gorm.DB) error
+	RollbackTx(ctx context.Context, tx *gorm.DB)
+
+	// functional
+	CreateNewRole(ctx context.Context, tx *gorm.DB, role entity.Role) (entity.Role, error)
+	GetAllRoles(ctx context.Context, tx *gorm.DB) ([]entity.Role, error)
+	GetRoleByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Role, error)
+	GetRoleByName(ctx context.Context, tx *gorm.DB, name string) (entity.Role, error)
+	UpdateRole(ctx context.Context, tx *gorm.DB, role entity.Role) (entity.Role, error)
+	ReplaceRolePermissions(ctx context.Context, tx *gorm.DB, role entity.Role, permissions []entity.Permission) error
+	DeleteRoleByID(ctx context.Context, tx *gorm.DB, id uint64) error
+	CountUsersWithRole(ctx context.Context, tx *gorm.DB, name string) (int64, error)
+	GetAllPermissions(ctx context.Context, tx *gorm.DB) ([]entity.Permission, error)
+	GetPermissionsByNames(ctx context.Context, tx *gorm.DB, names []string) ([]entity.Permission, error)
+}
+
+func NewRoleRepository(db *gorm.DB) *roleRepository {
+	return &roleRepository{db: db}
+}
+
+func (roleR *roleRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
+	tx := roleR.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tx, nil
+}
+
+func (roleR *roleRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).Commit().Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (roleR *roleRepository) RollbackTx(ctx context.Context, tx *gorm.DB) {
+	tx.WithContext(ctx).Debug().Rollback()
+}
+
+// CreateNewRole creates a role along with links to its permissions, which
+// must exist already
+func (roleR *roleRepository) CreateNewRole(ctx context.Context, tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	var err error
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Omit("Permissions.*").Create(&role)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit("Permissions.*").Create(&role).Error
+	}
+
+	if err != nil {
+		return entity.Role{}, err
+	}
+	return role, nil
+}
+
+func (roleR *roleRepository) GetAllRoles(ctx context.Context, tx *gorm.DB) ([]entity.Role, error) {
+	var err error
+	var roles []entity.Role
+
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Preload("Permissions").Order("name").Find(&roles)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Preload("Permissions").Order("name").Find(&roles).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return roles, err
+	}
+	return roles, nil
+}
+
+func (roleR *roleRepository) GetRoleByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Role, error) {
+	var err error
+	var role entity.Role
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Where("id = ?", id).Preload("Permissions").Take(&role)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = ?", id).Preload("Permissions").Take(&role).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return role, err
+	}
+	return role, nil
+}
+
+func (roleR *roleRepository) GetRoleByName(ctx context.Context, tx *gorm.DB, name string) (entity.Role, error) {
+	var err error
+	var role entity.Role
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Where("name = ?", name).Preload("Permissions").Take(&role)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("name = ?", name).Preload("Permissions").Take(&role).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return role, err
+	}
+	return role, nil
+}
+
+func (roleR *roleRepository) UpdateRole(ctx context.Context, tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	var err error
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Omit("Permissions").Save(&role)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit("Permissions").Save(&role).Error
+	}
+
+	if err != nil {
+		return role, err
+	}
+	return role, nil
+}
+
+// ReplaceRolePermissions sets the permissions of a role to exactly permissions
+func (roleR *roleRepository) ReplaceRolePermissions(ctx context.Context, tx *gorm.DB, role entity.Role, permissions []entity.Permission) error {
+	if tx == nil {
+		tx = roleR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&role).Omit("Permissions.*").Association("Permissions").Replace(permissions)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteRoleByID deletes the role and its links to permissions
+func (roleR *roleRepository) DeleteRoleByID(ctx context.Context, tx *gorm.DB, id uint64) error {
+	if tx == nil {
+		tx = roleR.db
+	}
+
+	return tx.WithContext(ctx).Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Role{}, id).Error
+	})
+}
+
+func (roleR *roleRepository) CountUsersWithRole(ctx context.Context, tx *gorm.DB, name string) (int64, error) {
+	if tx == nil {
+		tx = roleR.db
+	}
+
+	var count int64
+	err := tx.WithContext(ctx).Debug().Model(&entity.User{}).Where("role = ?", name).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (roleR *roleRepository) GetAllPermissions(ctx context.Context, tx *gorm.DB) ([]entity.Permission, error) {
+	var err error
+	var permissions []entity.Permission
+
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Order("name").Find(&permissions)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Order("name").Find(&permissions).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return permissions, err
+	}
+	return permissions, nil
+}
+
+func (roleR *roleRepository) GetPermissionsByNames(ctx context.Context, tx *gorm.DB, names []string) ([]entity.Permission, error) {
+	var err error
+	var permissions []entity.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	if tx == nil {
+		tx = roleR.db.WithContext(ctx).Debug().Where("name IN ?", names).Find(&permissions)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("name IN ?", names).Find(&permissions).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return permissions, err
+	}
+	return permissions, nil
+}
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"fp-rpl/common"
 	"fp-rpl/entity"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -19,12 +21,14 @@ type SessionRepository interface {
 	RollbackTx(ctx context.Context, tx *gorm.DB)
 
 	// functional
-	GetSessionByTimeAndAreaID(ctx context.Context, tx *gorm.DB, time string, areaID uint64) (entity.Session, error)
 	GetSessionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error)
 	CreateNewSession(ctx context.Context, tx *gorm.DB, session entity.Session) (entity.Session, error)
-	GetAllSessions(ctx context.Context, tx *gorm.DB) ([]entity.Session, error)
+	GetAllSessions(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Session, int64, error)
 	DeleteSessionByID(ctx context.Context, tx *gorm.DB, id uint64) error
 	GetSessionDetailByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error)
+	GetSessionForBookingByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error)
+	GetSessionsByAreaIDBetween(ctx context.Context, tx *gorm.DB, areaID uint64, from time.Time, to time.Time) ([]entity.Session, error)
+	GetShowtimes(ctx context.Context, tx *gorm.DB, from time.Time, to time.Time, filmSlug string, areaID uint64) ([]entity.Session, error)
 }
 
 func NewSessionRepository(db *gorm.DB) *sessionRepository {
@@ -41,7 +45,7 @@ func (sessionR *sessionRepository) BeginTx(ctx context.Context) (*gorm.DB, error
 
 func (sessionR *sessionRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -51,22 +55,6 @@ func (sessionR *sessionRepository) RollbackTx(ctx context.Context, tx *gorm.DB)
 	tx.WithContext(ctx).Debug().Rollback()
 }
 
-func (sessionR *sessionRepository) GetSessionByTimeAndAreaID(ctx context.Context, tx *gorm.DB, time string, areaID uint64) (entity.Session, error) {
-	var err error
-	var session entity.Session
-	if tx == nil {
-		tx = sessionR.db.WithContext(ctx).Debug().Where("time = $1 AND area_id = $2", time, areaID).Take(&session)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Where("time = $1 AND area_id = $2", time, areaID).Take(&session).Error
-	}
-
-	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return session, err
-	}
-	return session, nil
-}
-
 func (sessionR *sessionRepository) GetSessionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error) {
 	var err error
 	var session entity.Session
@@ -98,21 +86,23 @@ func (sessionR *sessionRepository) CreateNewSession(ctx context.Context, tx *gor
 	return session, nil
 }
 
-func (sessionR *sessionRepository) GetAllSessions(ctx context.Context, tx *gorm.DB) ([]entity.Session, error) {
-	var err error
+func (sessionR *sessionRepository) GetAllSessions(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Session, int64, error) {
 	var sessions []entity.Session
-
+	var total int64
 	if tx == nil {
-		tx = sessionR.db.WithContext(ctx).Debug().Find(&sessions)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Find(&sessions).Error
+		tx = sessionR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.Session{}).Scopes(query.Filter).Count(&total).Error
+	if err != nil {
+		return sessions, 0, err
 	}
 
+	err = tx.WithContext(ctx).Debug().Scopes(query.Paginate).Find(&sessions).Error
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return sessions, err
+		return sessions, 0, err
 	}
-	return sessions, nil
+	return sessions, total, nil
 }
 
 func (sessionR *sessionRepository) DeleteSessionByID(ctx context.Context, tx *gorm.DB, id uint64) error {
@@ -130,14 +120,17 @@ func (sessionR *sessionRepository) DeleteSessionByID(ctx context.Context, tx *go
 	return nil
 }
 
+// GetSessionDetailByID returns a session with its film and area. Its spots
+// and transactions are left out as they tell who bought what, the seat map
+// shows the state of the spots instead.
 func (sessionR *sessionRepository) GetSessionDetailByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error) {
 	var err error
 	var session entity.Session
 	if tx == nil {
-		tx = sessionR.db.WithContext(ctx).Debug().Where("id = $1", id).Preload("Transactions").Preload("Spots").Take(&session)
+		tx = sessionR.db.WithContext(ctx).Debug().Where("id = $1", id).Preload("Film").Preload("Area").Take(&session)
 		err = tx.Error
 	} else {
-		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Preload("Transactions").Preload("Spots").Take(&session).Error
+		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Preload("Film").Preload("Area").Take(&session).Error
 	}
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
@@ -145,3 +138,61 @@ func (sessionR *sessionRepository) GetSessionDetailByID(ctx context.Context, tx
 	}
 	return session, nil
 }
+
+func (sessionR *sessionRepository) GetSessionForBookingByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Session, error) {
+	var err error
+	var session entity.Session
+	if tx == nil {
+		tx = sessionR.db.WithContext(ctx).Debug().Where("id = $1", id).Preload("Area").Preload("Film").Take(&session)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Preload("Area").Preload("Film").Take(&session).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return session, err
+	}
+	return session, nil
+}
+
+// GetSessionsByAreaIDBetween returns the sessions of an area that are running
+// at some point between from and to
+func (sessionR *sessionRepository) GetSessionsByAreaIDBetween(ctx context.Context, tx *gorm.DB, areaID uint64, from time.Time, to time.Time) ([]entity.Session, error) {
+	var err error
+	var sessions []entity.Session
+
+	if tx == nil {
+		tx = sessionR.db.WithContext(ctx).Debug().Where("area_id = $1 AND starts_at < $2 AND ends_at > $3", areaID, to, from).Order("starts_at").Find(&sessions)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("area_id = $1 AND starts_at < $2 AND ends_at > $3", areaID, to, from).Order("starts_at").Find(&sessions).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return sessions, err
+	}
+	return sessions, nil
+}
+
+// GetShowtimes returns the sessions starting between from and to with their
+// film and area, optionally only those of one film or area
+func (sessionR *sessionRepository) GetShowtimes(ctx context.Context, tx *gorm.DB, from time.Time, to time.Time, filmSlug string, areaID uint64) ([]entity.Session, error) {
+	var sessions []entity.Session
+	if tx == nil {
+		tx = sessionR.db
+	}
+
+	query := tx.WithContext(ctx).Debug().Joins("Film").Preload("Area").Where("sessions.starts_at >= ? AND sessions.starts_at < ?", from, to)
+	if filmSlug != "" {
+		query = query.Where(`"Film".slug = ?`, filmSlug)
+	}
+	if areaID != 0 {
+		query = query.Where("sessions.area_id = ?", areaID)
+	}
+
+	err := query.Order("sessions.starts_at").Find(&sessions).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return sessions, err
+	}
+	return sessions, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fp-rpl/entity"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type spotRepository struct {
@@ -20,9 +22,17 @@ type SpotRepository interface {
 
 	// functional
 	CreateNewSpot(ctx context.Context, tx *gorm.DB, spot entity.Spot) (entity.Spot, error)
+	CreateNewSpots(ctx context.Context, tx *gorm.DB, spots []entity.Spot) ([]entity.Spot, error)
 	DeleteSpotsBySessionID(ctx context.Context, tx *gorm.DB, sessionID uint64) error
 	GetSpotBySessionIDAndAttributes(ctx context.Context, tx *gorm.DB, sessionID uint64, spotRow string, spotNumber int) (entity.Spot, error)
+	GetSpotsBySessionID(ctx context.Context, tx *gorm.DB, sessionID uint64) ([]entity.Spot, error)
+	LockSpotBySessionIDAndAttributes(ctx context.Context, tx *gorm.DB, sessionID uint64, spotRow string, spotNumber int) (entity.Spot, error)
 	UpdateSpot(ctx context.Context, tx *gorm.DB, spot entity.Spot) (entity.Spot, error)
+	ReleaseExpiredHolds(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.Spot, error)
+	ReleaseSpotsByTransactionID(ctx context.Context, tx *gorm.DB, transactionID uint64) ([]entity.Spot, error)
+	CountAvailableSpotsBySessionIDs(ctx context.Context, tx *gorm.DB, sessionIDs []uint64, now time.Time) (map[uint64]int, error)
+	LockUserHolds(ctx context.Context, tx *gorm.DB, sessionID uint64, userID uint64) error
+	CountActiveHoldsOfUser(ctx context.Context, tx *gorm.DB, sessionID uint64, userID uint64, now time.Time) (int64, error)
 }
 
 func NewSpotRepository(db *gorm.DB) *spotRepository {
@@ -39,7 +49,7 @@ func (spotR *spotRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
 
 func (spotR *spotRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -64,6 +74,25 @@ func (spotR *spotRepository) CreateNewSpot(ctx context.Context, tx *gorm.DB, spo
 	return spot, nil
 }
 
+func (spotR *spotRepository) CreateNewSpots(ctx context.Context, tx *gorm.DB, spots []entity.Spot) ([]entity.Spot, error) {
+	if len(spots) == 0 {
+		return spots, nil
+	}
+
+	var err error
+	if tx == nil {
+		tx = spotR.db.WithContext(ctx).Debug().CreateInBatches(&spots, 100)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().CreateInBatches(&spots, 100).Error
+	}
+
+	if err != nil {
+		return []entity.Spot{}, err
+	}
+	return spots, nil
+}
+
 func (spotR *spotRepository) DeleteSpotsBySessionID(ctx context.Context, tx *gorm.DB, sessionID uint64) error {
 	var err error
 	if tx == nil {
@@ -95,6 +124,38 @@ func (spotR *spotRepository) GetSpotBySessionIDAndAttributes(ctx context.Context
 	return spot, nil
 }
 
+func (spotR *spotRepository) GetSpotsBySessionID(ctx context.Context, tx *gorm.DB, sessionID uint64) ([]entity.Spot, error) {
+	var err error
+	var spots []entity.Spot
+
+	if tx == nil {
+		tx = spotR.db.WithContext(ctx).Debug().Where("session_id = $1", sessionID).Order("row").Order("number").Find(&spots)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("session_id = $1", sessionID).Order("row").Order("number").Find(&spots).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return spots, err
+	}
+	return spots, nil
+}
+
+// LockSpotBySessionIDAndAttributes takes the spot with SELECT ... FOR UPDATE,
+// so it must be called with an open db transaction
+func (spotR *spotRepository) LockSpotBySessionIDAndAttributes(ctx context.Context, tx *gorm.DB, sessionID uint64, spotRow string, spotNumber int) (entity.Spot, error) {
+	var spot entity.Spot
+	if tx == nil {
+		return spot, errors.New("locking a spot requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("session_id = $1 AND row = $2 AND number = $3", sessionID, spotRow, spotNumber).Take(&spot).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return spot, err
+	}
+	return spot, nil
+}
+
 func (spotR *spotRepository) UpdateSpot(ctx context.Context, tx *gorm.DB, spot entity.Spot) (entity.Spot, error) {
 	var err error
 	
@@ -109,4 +170,93 @@ func (spotR *spotRepository) UpdateSpot(ctx context.Context, tx *gorm.DB, spot e
 		return spot, err
 	}
 	return spot, nil
-}
\ No newline at end of file
+}
+
+// ReleaseExpiredHolds clears every hold that expired at now and returns the
+// released spots
+func (spotR *spotRepository) ReleaseExpiredHolds(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.Spot, error) {
+	var spots []entity.Spot
+	if tx == nil {
+		tx = spotR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&spots).Clauses(clause.Returning{}).Where("held_until <= ?", now).Updates(map[string]any{
+		"held_by_user_id": nil,
+		"held_until":      nil,
+	}).Error
+	if err != nil {
+		return []entity.Spot{}, err
+	}
+	return spots, nil
+}
+
+// ReleaseSpotsByTransactionID gives the spots of a transaction back to the
+// session and returns them
+func (spotR *spotRepository) ReleaseSpotsByTransactionID(ctx context.Context, tx *gorm.DB, transactionID uint64) ([]entity.Spot, error) {
+	var err error
+	var spots []entity.Spot
+	if tx == nil {
+		tx = spotR.db.WithContext(ctx).Debug().Model(&spots).Clauses(clause.Returning{}).Where("transaction_id = ?", transactionID).Update("transaction_id", nil)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Model(&spots).Clauses(clause.Returning{}).Where("transaction_id = ?", transactionID).Update("transaction_id", nil).Error
+	}
+
+	if err != nil {
+		return []entity.Spot{}, err
+	}
+	return spots, nil
+}
+
+// CountAvailableSpotsBySessionIDs counts the spots of every session that are
+// neither sold, blocked nor held at now
+func (spotR *spotRepository) CountAvailableSpotsBySessionIDs(ctx context.Context, tx *gorm.DB, sessionIDs []uint64, now time.Time) (map[uint64]int, error) {
+	available := map[uint64]int{}
+	if len(sessionIDs) == 0 {
+		return available, nil
+	}
+
+	if tx == nil {
+		tx = spotR.db
+	}
+
+	var counts []struct {
+		SessionID uint64
+		Available int
+	}
+	err := tx.WithContext(ctx).Debug().Model(&entity.Spot{}).Select("session_id, COUNT(*) AS available").
+		Where("session_id IN ? AND transaction_id IS NULL AND is_blocked = ? AND (held_until IS NULL OR held_until <= ?)", sessionIDs, false, now).
+		Group("session_id").Scan(&counts).Error
+	if err != nil {
+		return available, err
+	}
+
+	for _, count := range counts {
+		available[count.SessionID] = count.Available
+	}
+	return available, nil
+}
+
+// LockUserHolds serializes the hold requests of a user in a session until tx
+// ends, so concurrent requests can't go over the hold limit together
+func (spotR *spotRepository) LockUserHolds(ctx context.Context, tx *gorm.DB, sessionID uint64, userID uint64) error {
+	if tx == nil {
+		return errors.New("locking holds requires a db transaction")
+	}
+
+	return tx.WithContext(ctx).Debug().Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(sessionID), int32(userID)).Error
+}
+
+// CountActiveHoldsOfUser counts the spots of a session the user holds at now
+func (spotR *spotRepository) CountActiveHoldsOfUser(ctx context.Context, tx *gorm.DB, sessionID uint64, userID uint64, now time.Time) (int64, error) {
+	var count int64
+	if tx == nil {
+		tx = spotR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.Spot{}).Where("session_id = ? AND held_by_user_id = ? AND held_until > ?", sessionID, userID, now).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
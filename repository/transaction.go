@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"errors"
+	"fp-rpl/common"
 	"fp-rpl/entity"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type transactionRepository struct {
@@ -20,10 +23,16 @@ type TransactionRepository interface {
 
 	// functional
 	CreateNewTransaction(ctx context.Context, tx *gorm.DB, transaction entity.Transaction) (entity.Transaction, error)
-	GetAllTransactions(ctx context.Context, tx *gorm.DB) ([]entity.Transaction, error)
+	GetAllTransactions(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Transaction, int64, error)
 	GetTransactionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Transaction, error)
 	GetTransactionsByUserID(ctx context.Context, tx *gorm.DB, userID uint64) ([]entity.Transaction, error)
 	DeleteTransactionByID(ctx context.Context, tx *gorm.DB, id uint64) error
+	GetTransactionByCode(ctx context.Context, tx *gorm.DB, code string) (entity.Transaction, error)
+	LockTransactionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Transaction, error)
+	UpdateTransaction(ctx context.Context, tx *gorm.DB, transaction entity.Transaction) (entity.Transaction, error)
+	GetUnsettledTransactions(ctx context.Context, tx *gorm.DB, updatedBefore time.Time) ([]entity.Transaction, error)
+	GetExpiredPendingTransactions(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.Transaction, error)
+	CountActiveTransactionsByPromoCodeAndUserID(ctx context.Context, tx *gorm.DB, promoCodeID uint64, userID uint64) (int64, error)
 }
 
 func NewTransactionRepository(db *gorm.DB) *transactionRepository {
@@ -40,7 +49,7 @@ func (transactionR *transactionRepository) BeginTx(ctx context.Context) (*gorm.D
 
 func (transactionR *transactionRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -65,31 +74,33 @@ func (transactionR *transactionRepository) CreateNewTransaction(ctx context.Cont
 	return transaction, nil
 }
 
-func (transactionR *transactionRepository) GetAllTransactions(ctx context.Context, tx *gorm.DB) ([]entity.Transaction, error) {
-	var err error
+func (transactionR *transactionRepository) GetAllTransactions(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.Transaction, int64, error) {
 	var transactions []entity.Transaction
-
+	var total int64
 	if tx == nil {
-		tx = transactionR.db.WithContext(ctx).Debug().Preload("Spots").Find(&transactions)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Preload("Spots").Find(&transactions).Error
+		tx = transactionR.db
 	}
 
+	err := tx.WithContext(ctx).Debug().Model(&entity.Transaction{}).Scopes(query.Filter).Count(&total).Error
+	if err != nil {
+		return transactions, 0, err
+	}
+
+	err = tx.WithContext(ctx).Debug().Preload("Spots").Preload("Lines").Scopes(query.Paginate).Find(&transactions).Error
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return transactions, err
+		return transactions, 0, err
 	}
-	return transactions, nil
+	return transactions, total, nil
 }
 
 func (transactionR *transactionRepository) GetTransactionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Transaction, error) {
 	var err error
 	var transaction entity.Transaction
 	if tx == nil {
-		tx = transactionR.db.WithContext(ctx).Debug().Where("id = $1", id).Preload("Spots").Take(&transaction)
+		tx = transactionR.db.WithContext(ctx).Debug().Where("id = $1", id).Preload("Spots").Preload("Lines").Take(&transaction)
 		err = tx.Error
 	} else {
-		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Preload("Spots").Take(&transaction).Error
+		err = tx.WithContext(ctx).Debug().Where("id = $1", id).Preload("Spots").Preload("Lines").Take(&transaction).Error
 	}
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
@@ -103,10 +114,10 @@ func (transactionR *transactionRepository) GetTransactionsByUserID(ctx context.C
 	var transactions []entity.Transaction
 
 	if tx == nil {
-		tx = transactionR.db.WithContext(ctx).Debug().Where("user_id = $1", userID).Preload("Spots").Find(&transactions)
+		tx = transactionR.db.WithContext(ctx).Debug().Where("user_id = $1", userID).Preload("Spots").Preload("Lines").Find(&transactions)
 		err = tx.Error
 	} else {
-		err = tx.WithContext(ctx).Debug().Where("user_id = $1", userID).Preload("Spots").Find(&transactions).Error
+		err = tx.WithContext(ctx).Debug().Where("user_id = $1", userID).Preload("Spots").Preload("Lines").Find(&transactions).Error
 	}
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
@@ -129,3 +140,100 @@ func (transactionR *transactionRepository) DeleteTransactionByID(ctx context.Con
 	}
 	return nil
 }
+
+func (transactionR *transactionRepository) GetTransactionByCode(ctx context.Context, tx *gorm.DB, code string) (entity.Transaction, error) {
+	var err error
+	var transaction entity.Transaction
+	if tx == nil {
+		tx = transactionR.db.WithContext(ctx).Debug().Where("code = $1", code).Preload("Spots").Preload("Lines").Take(&transaction)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("code = $1", code).Preload("Spots").Preload("Lines").Take(&transaction).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return transaction, err
+	}
+	return transaction, nil
+}
+
+// LockTransactionByID takes the transaction with SELECT ... FOR UPDATE,
+// so it must be called with an open db transaction
+func (transactionR *transactionRepository) LockTransactionByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.Transaction, error) {
+	var transaction entity.Transaction
+	if tx == nil {
+		return transaction, errors.New("locking a transaction requires a db transaction")
+	}
+
+	err := tx.WithContext(ctx).Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = $1", id).Take(&transaction).Error
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return transaction, err
+	}
+	return transaction, nil
+}
+
+func (transactionR *transactionRepository) UpdateTransaction(ctx context.Context, tx *gorm.DB, transaction entity.Transaction) (entity.Transaction, error) {
+	var err error
+	if tx == nil {
+		tx = transactionR.db.WithContext(ctx).Debug().Omit(clause.Associations).Save(&transaction)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Omit(clause.Associations).Save(&transaction).Error
+	}
+
+	if err != nil {
+		return transaction, err
+	}
+	return transaction, nil
+}
+
+func (transactionR *transactionRepository) GetExpiredPendingTransactions(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.Transaction, error) {
+	var err error
+	var transactions []entity.Transaction
+
+	if tx == nil {
+		tx = transactionR.db.WithContext(ctx).Debug().Where("status = $1 AND expires_at <= $2", entity.TransactionPending, now).Find(&transactions)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("status = $1 AND expires_at <= $2", entity.TransactionPending, now).Find(&transactions).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return transactions, err
+	}
+	return transactions, nil
+}
+
+// GetUnsettledTransactions returns the transactions last changed before
+// updatedBefore that are still being charged or are owed a refund
+func (transactionR *transactionRepository) GetUnsettledTransactions(ctx context.Context, tx *gorm.DB, updatedBefore time.Time) ([]entity.Transaction, error) {
+	var err error
+	var transactions []entity.Transaction
+
+	if tx == nil {
+		tx = transactionR.db.WithContext(ctx).Debug().Where("(status = $1 OR (refund_requested_at IS NOT NULL AND refunded_at IS NULL)) AND updated_at <= $2", entity.TransactionPaymentPending, updatedBefore).Find(&transactions)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Where("(status = $1 OR (refund_requested_at IS NOT NULL AND refunded_at IS NULL)) AND updated_at <= $2", entity.TransactionPaymentPending, updatedBefore).Find(&transactions).Error
+	}
+
+	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
+		return transactions, err
+	}
+	return transactions, nil
+}
+
+// CountActiveTransactionsByPromoCodeAndUserID counts the pending, paying and
+// paid transactions of a user that used the promo code
+func (transactionR *transactionRepository) CountActiveTransactionsByPromoCodeAndUserID(ctx context.Context, tx *gorm.DB, promoCodeID uint64, userID uint64) (int64, error) {
+	var count int64
+	if tx == nil {
+		tx = transactionR.db
+	}
+
+	err := tx.WithContext(ctx).Debug().Model(&entity.Transaction{}).Where("promo_code_id = ? AND user_id = ? AND status IN ?", promoCodeID, userID, []string{entity.TransactionPending, entity.TransactionPaymentPending, entity.TransactionPaid}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"fp-rpl/common"
 	"fp-rpl/entity"
+	"fp-rpl/utils"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -22,8 +25,13 @@ type UserRepository interface {
 	CreateNewUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error)
 	GetUserByIdentifier(ctx context.Context, tx *gorm.DB, username string, email string) (entity.User, error)
 	GetUserByID(ctx context.Context, tx *gorm.DB, id uint64) (entity.User, error)
-	GetAllUsers(ctx context.Context, tx *gorm.DB) ([]entity.User, error)
+	GetAllUsers(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.User, int64, error)
 	UpdateNameUser(ctx context.Context, tx *gorm.DB, name string, user entity.User) (entity.User, error)
+	UpdateBirthDateUser(ctx context.Context, tx *gorm.DB, birthDate time.Time, user entity.User) (entity.User, error)
+	UpdateRoleUser(ctx context.Context, tx *gorm.DB, role string, user entity.User) (entity.User, error)
+	UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error)
+	UpdatePasswordUser(ctx context.Context, tx *gorm.DB, password string, user entity.User) (entity.User, error)
+	UpdateSuspensionUser(ctx context.Context, tx *gorm.DB, suspendedAt *time.Time, user entity.User) (entity.User, error)
 	DeleteUserByID(ctx context.Context, tx *gorm.DB, id uint64) error
 }
 
@@ -41,7 +49,7 @@ func (userR *userRepository) BeginTx(ctx context.Context) (*gorm.DB, error) {
 
 func (userR *userRepository) CommitTx(ctx context.Context, tx *gorm.DB) error {
 	err := tx.WithContext(ctx).Commit().Error
-	if err == nil {
+	if err != nil {
 		return err
 	}
 	return nil
@@ -98,21 +106,23 @@ func (userR *userRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id ui
 	return user, nil
 }
 
-func (userR *userRepository) GetAllUsers(ctx context.Context, tx *gorm.DB) ([]entity.User, error) {
-	var err error
+func (userR *userRepository) GetAllUsers(ctx context.Context, tx *gorm.DB, query common.Query) ([]entity.User, int64, error) {
 	var users []entity.User
-
+	var total int64
 	if tx == nil {
-		tx = userR.db.WithContext(ctx).Debug().Find(&users)
-		err = tx.Error
-	} else {
-		err = tx.WithContext(ctx).Debug().Find(&users).Error
+		tx = userR.db
 	}
 
+	err := tx.WithContext(ctx).Debug().Model(&entity.User{}).Scopes(query.Filter).Count(&total).Error
+	if err != nil {
+		return users, 0, err
+	}
+
+	err = tx.WithContext(ctx).Debug().Scopes(query.Paginate).Find(&users).Error
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound)) {
-		return users, err
+		return users, 0, err
 	}
-	return users, nil
+	return users, total, nil
 }
 
 func (userR *userRepository) UpdateNameUser(ctx context.Context, tx *gorm.DB, name string, user entity.User) (entity.User, error) {
@@ -132,6 +142,95 @@ func (userR *userRepository) UpdateNameUser(ctx context.Context, tx *gorm.DB, na
 	return userUpdate, nil
 }
 
+func (userR *userRepository) UpdateBirthDateUser(ctx context.Context, tx *gorm.DB, birthDate time.Time, user entity.User) (entity.User, error) {
+	var err error
+	userUpdate := user
+	userUpdate.BirthDate = &birthDate
+	if tx == nil {
+		tx = userR.db.WithContext(ctx).Debug().Save(&userUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&userUpdate).Error
+	}
+
+	if err != nil {
+		return userUpdate, err
+	}
+	return userUpdate, nil
+}
+
+func (userR *userRepository) UpdateRoleUser(ctx context.Context, tx *gorm.DB, role string, user entity.User) (entity.User, error) {
+	var err error
+	userUpdate := user
+	userUpdate.Role = role
+	if tx == nil {
+		tx = userR.db.WithContext(ctx).Debug().Save(&userUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&userUpdate).Error
+	}
+
+	if err != nil {
+		return userUpdate, err
+	}
+	return userUpdate, nil
+}
+
+func (userR *userRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	var err error
+	if tx == nil {
+		tx = userR.db.WithContext(ctx).Debug().Save(&user)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&user).Error
+	}
+
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// UpdatePasswordUser hashes and stores a new password. Save doesn't count as
+// a change of the password for User.BeforeUpdate, so it is hashed here.
+func (userR *userRepository) UpdatePasswordUser(ctx context.Context, tx *gorm.DB, password string, user entity.User) (entity.User, error) {
+	hash, err := utils.PasswordHash(password)
+	if err != nil {
+		return user, err
+	}
+
+	userUpdate := user
+	userUpdate.Password = hash
+	if tx == nil {
+		tx = userR.db.WithContext(ctx).Debug().Save(&userUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&userUpdate).Error
+	}
+
+	if err != nil {
+		return userUpdate, err
+	}
+	return userUpdate, nil
+}
+
+func (userR *userRepository) UpdateSuspensionUser(ctx context.Context, tx *gorm.DB, suspendedAt *time.Time, user entity.User) (entity.User, error) {
+	var err error
+	userUpdate := user
+	userUpdate.SuspendedAt = suspendedAt
+	if tx == nil {
+		tx = userR.db.WithContext(ctx).Debug().Save(&userUpdate)
+		err = tx.Error
+	} else {
+		err = tx.WithContext(ctx).Debug().Save(&userUpdate).Error
+	}
+
+	if err != nil {
+		return userUpdate, err
+	}
+	return userUpdate, nil
+}
+
 func (userR *userRepository) DeleteUserByID(ctx context.Context, tx *gorm.DB, id uint64) error {
 	var err error
 	if tx == nil {
@@ -2,19 +2,21 @@ package routes
 
 import (
 	"fp-rpl/controller"
+	"fp-rpl/entity"
 	"fp-rpl/middleware"
 	"fp-rpl/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AreaRoutes(router *gin.Engine, areaC controller.AreaController) {
+func AreaRoutes(router *gin.Engine, areaC controller.AreaController, authS service.AuthService) {
 	areaRoutes := router.Group("/api/v1/areas")
 	{
-		areaRoutes.POST("", middleware.Authenticate(service.NewJWTService(), "admin"), areaC.CreateArea)
+		areaRoutes.POST("", middleware.Authenticate(authS, entity.PermissionAreasManage), areaC.CreateArea)
 		areaRoutes.GET("", areaC.GetAllAreas)
-		areaRoutes.GET("/:id", middleware.Authenticate(service.NewJWTService(), "admin"), areaC.GetAreaByID)
-		areaRoutes.PUT("/:id", middleware.Authenticate(service.NewJWTService(), "admin"), areaC.UpdateAreaByID)
-		areaRoutes.DELETE("/:id", middleware.Authenticate(service.NewJWTService(), "admin"), areaC.DeleteAreaByID)
+		areaRoutes.GET("/:id", middleware.Authenticate(authS, entity.PermissionAreasManage), areaC.GetAreaByID)
+		areaRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionAreasManage), areaC.UpdateAreaByID)
+		areaRoutes.PUT("/:id/layout", middleware.Authenticate(authS, entity.PermissionAreasManage), areaC.UpdateAreaLayoutByID)
+		areaRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionAreasManage), areaC.DeleteAreaByID)
 	}
 }
\ No newline at end of file
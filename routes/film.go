@@ -2,21 +2,27 @@ package routes
 
 import (
 	"fp-rpl/controller"
+	"fp-rpl/entity"
 	"fp-rpl/middleware"
 	"fp-rpl/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func FilmRoutes(router *gin.Engine, filmC controller.FilmController) {
+func FilmRoutes(router *gin.Engine, filmC controller.FilmController, authS service.AuthService) {
 	filmRoutes := router.Group("/api/v1/films")
 	{
-		filmRoutes.POST("", middleware.Authenticate(service.NewJWTService(), "admin"), filmC.CreateFilm)
+		filmRoutes.POST("", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.CreateFilm)
 		filmRoutes.GET("", filmC.GetAllFilmsNowPlaying)
 		filmRoutes.GET("/coming-soon", filmC.GetAllFilmsComingSoon)
 		filmRoutes.GET("/all", filmC.GetAllFilms)
-		filmRoutes.PUT("/:slug", middleware.Authenticate(service.NewJWTService(), "admin"), filmC.UpdateFilm)
+		filmRoutes.GET("/search", filmC.SearchFilms)
+		filmRoutes.PUT("/:slug", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.UpdateFilm)
 		filmRoutes.GET("/:slug", filmC.GetFilmDetailBySlug)
-		filmRoutes.DELETE("/:slug", middleware.Authenticate(service.NewJWTService(), "admin"), filmC.DeleteFilm)
+		filmRoutes.DELETE("/:slug", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.DeleteFilm)
+		filmRoutes.POST("/:slug/poster", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.UploadFilmPoster)
+		filmRoutes.DELETE("/:slug/poster", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.DeleteFilmPoster)
+		filmRoutes.POST("/:slug/trailer", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.UploadFilmTrailer)
+		filmRoutes.DELETE("/:slug/trailer", middleware.Authenticate(authS, entity.PermissionFilmsManage), filmC.DeleteFilmTrailer)
 	}
 }
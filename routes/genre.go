@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+	"fp-rpl/entity"
+	"fp-rpl/middleware"
+	"fp-rpl/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GenreRoutes(router *gin.Engine, genreC controller.GenreController, authS service.AuthService) {
+	genreRoutes := router.Group("/api/v1/genres")
+	{
+		genreRoutes.POST("", middleware.Authenticate(authS, entity.PermissionFilmsManage), genreC.CreateGenre)
+		genreRoutes.GET("", genreC.GetAllGenres)
+		genreRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionFilmsManage), genreC.UpdateGenreByID)
+		genreRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionFilmsManage), genreC.DeleteGenreByID)
+	}
+}
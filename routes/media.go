@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func MediaRoutes(router *gin.Engine, mediaC controller.MediaController) {
+	mediaRoutes := router.Group("/media")
+	{
+		mediaRoutes.GET("/*key", mediaC.GetMedia)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+	"fp-rpl/entity"
+	"fp-rpl/middleware"
+	"fp-rpl/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PersonRoutes(router *gin.Engine, personC controller.PersonController, authS service.AuthService) {
+	personRoutes := router.Group("/api/v1/people")
+	{
+		personRoutes.POST("", middleware.Authenticate(authS, entity.PermissionFilmsManage), personC.CreatePerson)
+		personRoutes.GET("", personC.GetAllPeople)
+		personRoutes.GET("/:id", personC.GetPersonByID)
+		personRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionFilmsManage), personC.UpdatePersonByID)
+		personRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionFilmsManage), personC.DeletePersonByID)
+	}
+}
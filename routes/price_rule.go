@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+	"fp-rpl/entity"
+	"fp-rpl/middleware"
+	"fp-rpl/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PriceRuleRoutes(router *gin.Engine, priceRuleC controller.PriceRuleController, authS service.AuthService) {
+	priceRuleRoutes := router.Group("/api/v1/price-rules")
+	{
+		priceRuleRoutes.POST("", middleware.Authenticate(authS, entity.PermissionPricingManage), priceRuleC.CreatePriceRule)
+		priceRuleRoutes.GET("", middleware.Authenticate(authS, entity.PermissionPricingManage), priceRuleC.GetAllPriceRules)
+		priceRuleRoutes.GET("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), priceRuleC.GetPriceRuleByID)
+		priceRuleRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), priceRuleC.UpdatePriceRuleByID)
+		priceRuleRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), priceRuleC.DeletePriceRuleByID)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+	"fp-rpl/entity"
+	"fp-rpl/middleware"
+	"fp-rpl/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PromoCodeRoutes(router *gin.Engine, promoCodeC controller.PromoCodeController, authS service.AuthService) {
+	promoCodeRoutes := router.Group("/api/v1/promo-codes")
+	{
+		promoCodeRoutes.POST("", middleware.Authenticate(authS, entity.PermissionPricingManage), promoCodeC.CreatePromoCode)
+		promoCodeRoutes.GET("", middleware.Authenticate(authS, entity.PermissionPricingManage), promoCodeC.GetAllPromoCodes)
+		promoCodeRoutes.GET("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), promoCodeC.GetPromoCodeByID)
+		promoCodeRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), promoCodeC.UpdatePromoCodeByID)
+		promoCodeRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionPricingManage), promoCodeC.DeletePromoCodeByID)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+	"fp-rpl/entity"
+	"fp-rpl/middleware"
+	"fp-rpl/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RoleRoutes(router *gin.Engine, roleC controller.RoleController, authS service.AuthService) {
+	roleRoutes := router.Group("/api/v1/roles")
+	{
+		roleRoutes.GET("", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.GetAllRoles)
+		roleRoutes.POST("", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.CreateRole)
+		roleRoutes.GET("/:id", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.GetRoleByID)
+		roleRoutes.PUT("/:id", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.UpdateRoleByID)
+		roleRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.DeleteRoleByID)
+	}
+
+	permissionRoutes := router.Group("/api/v1/permissions")
+	{
+		permissionRoutes.GET("", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.GetAllPermissions)
+	}
+
+	userRoleRoutes := router.Group("/api/v1/users")
+	{
+		userRoleRoutes.PUT("/:username/role", middleware.Authenticate(authS, entity.PermissionRolesManage), roleC.UpdateUserRole)
+	}
+}
@@ -2,19 +2,25 @@ package routes
 
 import (
 	"fp-rpl/controller"
+	"fp-rpl/entity"
 	"fp-rpl/middleware"
 	"fp-rpl/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SessionRoutes(router *gin.Engine, sessionC controller.SessionController) {
+func SessionRoutes(router *gin.Engine, sessionC controller.SessionController, authS service.AuthService) {
 	sessionRoutes := router.Group("/api/v1/sessions")
 	{
-		sessionRoutes.POST("", middleware.Authenticate(service.NewJWTService(), "admin"), sessionC.CreateSession)
-		sessionRoutes.GET("", middleware.Authenticate(service.NewJWTService(), "admin"), sessionC.GetAllSessions)
-		sessionRoutes.DELETE("/:id", middleware.Authenticate(service.NewJWTService(), "admin"), sessionC.DeleteSessionByID)
-		
+		sessionRoutes.POST("", middleware.Authenticate(authS, entity.PermissionSessionsManage), sessionC.CreateSession)
+		sessionRoutes.POST("/schedule", middleware.Authenticate(authS, entity.PermissionSessionsManage), sessionC.ScheduleSessions)
+		sessionRoutes.GET("/conflicts", middleware.Authenticate(authS, entity.PermissionSessionsManage), sessionC.CheckSessionConflicts)
+		sessionRoutes.GET("", middleware.Authenticate(authS, entity.PermissionSessionsManage), sessionC.GetAllSessions)
+		sessionRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionSessionsManage), sessionC.DeleteSessionByID)
+		sessionRoutes.GET("/:id/seatmap", sessionC.GetSessionSeatMap)
+		sessionRoutes.GET("/:id/stream", sessionC.StreamSeatEvents)
+		sessionRoutes.POST("/:id/holds", middleware.Authenticate(authS), sessionC.HoldSpots)
+		sessionRoutes.DELETE("/:id/holds", middleware.Authenticate(authS), sessionC.ReleaseSpotHolds)
 	}
 
 	sessionFilmRoutes := router.Group("/api/v1/sessions/films")
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"fp-rpl/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ShowtimeRoutes(router *gin.Engine, sessionC controller.SessionController) {
+	showtimeRoutes := router.Group("/api/v1/showtimes")
+	{
+		showtimeRoutes.GET("", sessionC.GetShowtimes)
+	}
+}
@@ -2,27 +2,33 @@ package routes
 
 import (
 	"fp-rpl/controller"
+	"fp-rpl/entity"
 	"fp-rpl/middleware"
 	"fp-rpl/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TransactionRoutes(router *gin.Engine, transactionC controller.TransactionController) {
+func TransactionRoutes(router *gin.Engine, transactionC controller.TransactionController, authS service.AuthService) {
 	transactionRoutes := router.Group("/api/v1/transactions")
 	{
-		transactionRoutes.GET("", middleware.Authenticate(service.NewJWTService(), "admin"), transactionC.GetAllTransactions)
-		transactionRoutes.GET("/me", middleware.Authenticate(service.NewJWTService(), "user"), transactionC.GetMyTransactions)
-		transactionRoutes.DELETE("/:id", middleware.Authenticate(service.NewJWTService(), "admin"), transactionC.DeleteTransactionByID)
+		transactionRoutes.GET("", middleware.Authenticate(authS, entity.PermissionTransactionsRead), transactionC.GetAllTransactions)
+		transactionRoutes.GET("/me", middleware.Authenticate(authS), transactionC.GetMyTransactions)
+		transactionRoutes.DELETE("/:id", middleware.Authenticate(authS, entity.PermissionTransactionsManage), transactionC.DeleteTransactionByID)
+		transactionRoutes.POST("/me/:code/pay", middleware.Authenticate(authS), transactionC.PayMyTransaction)
+		transactionRoutes.POST("/me/:code/cancel", middleware.Authenticate(authS), transactionC.CancelMyTransaction)
+		transactionRoutes.POST("/:id/confirm", middleware.Authenticate(authS, entity.PermissionTransactionsManage), transactionC.ConfirmTransaction)
+		transactionRoutes.POST("/:id/cancel", middleware.Authenticate(authS, entity.PermissionTransactionsManage), transactionC.CancelTransaction)
+		transactionRoutes.POST("/:id/refund", middleware.Authenticate(authS, entity.PermissionTransactionsRefund), transactionC.RefundTransaction)
 	}
 
 	transactionUserRoutes := router.Group("/api/v1/transactions/users")
 	{
-		transactionUserRoutes.GET("/:username", middleware.Authenticate(service.NewJWTService(), "admin"), transactionC.GetTransactionsByUsername)
+		transactionUserRoutes.GET("/:username", middleware.Authenticate(authS, entity.PermissionTransactionsRead), transactionC.GetTransactionsByUsername)
 	}
 
 	transactionSessionRoutes := router.Group("/api/v1/transactions/sessions")
 	{
-		transactionSessionRoutes.POST("/:sessionid", middleware.Authenticate(service.NewJWTService(), "user"), transactionC.MakeTransaction)
+		transactionSessionRoutes.POST("/:sessionid", middleware.Authenticate(authS), transactionC.MakeTransaction)
 	}
 }
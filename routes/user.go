@@ -2,21 +2,33 @@ package routes
 
 import (
 	"fp-rpl/controller"
+	"fp-rpl/entity"
 	"fp-rpl/middleware"
 	"fp-rpl/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(router *gin.Engine, userC controller.UserController) {
+func UserRoutes(router *gin.Engine, userC controller.UserController, authS service.AuthService) {
 	userRoutes := router.Group("/api/v1/users")
 	{
-		userRoutes.GET("", middleware.Authenticate(service.NewJWTService(), "admin"), userC.GetAllUsers)
-		userRoutes.GET("/:username", middleware.Authenticate(service.NewJWTService(), "user"), userC.GetUserByUsername)
-		userRoutes.GET("/me", middleware.Authenticate(service.NewJWTService(), "user"), userC.GetMe)
-		userRoutes.PUT("/name", middleware.Authenticate(service.NewJWTService(), "user"), userC.UpdateSelfName)
-		userRoutes.DELETE("", middleware.Authenticate(service.NewJWTService(), "user"), userC.DeleteSelfUser)
+		userRoutes.GET("", middleware.Authenticate(authS, entity.PermissionUsersRead), userC.GetAllUsers)
+		userRoutes.GET("/:username", middleware.Authenticate(authS), userC.GetUserByUsername)
+		userRoutes.GET("/me", middleware.Authenticate(authS), userC.GetMe)
+		userRoutes.PUT("/name", middleware.Authenticate(authS), userC.UpdateSelfName)
+		userRoutes.PUT("/birth-date", middleware.Authenticate(authS), userC.UpdateSelfBirthDate)
+		userRoutes.PUT("/password", middleware.Authenticate(authS), userC.UpdateSelfPassword)
+		userRoutes.DELETE("", middleware.Authenticate(authS), userC.DeleteSelfUser)
 		userRoutes.POST("", userC.Register)
 		userRoutes.POST("/login", userC.Login)
+		userRoutes.POST("/refresh", userC.Refresh)
+		userRoutes.POST("/password/forgot", userC.ForgotPassword)
+		userRoutes.POST("/password/reset", userC.RecoverPassword)
+		userRoutes.POST("/logout", middleware.Authenticate(authS), userC.Logout)
+		userRoutes.POST("/staff", middleware.Authenticate(authS, entity.PermissionUsersManage), userC.CreateUser)
+		userRoutes.PUT("/:username", middleware.Authenticate(authS, entity.PermissionUsersManage), userC.UpdateUserByUsername)
+		userRoutes.POST("/:username/suspend", middleware.Authenticate(authS, entity.PermissionUsersManage), userC.SuspendUser)
+		userRoutes.POST("/:username/reactivate", middleware.Authenticate(authS, entity.PermissionUsersManage), userC.ReactivateUser)
+		userRoutes.POST("/:username/password-reset", middleware.Authenticate(authS, entity.PermissionUsersManage), userC.ResetUserPassword)
 	}
 }
@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs job in the background once per interval until ctx is cancelled
+func Every(ctx context.Context, interval time.Duration, name string, job func(ctx context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := job(ctx)
+				if err != nil {
+					log.Println("scheduler:", name, "failed:", err)
+				}
+			}
+		}
+	}()
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
+	"strings"
 
 	"github.com/jinzhu/copier"
 )
@@ -16,10 +18,11 @@ type areaService struct {
 type AreaService interface {
 	GetAreaByName(ctx context.Context, name string) (entity.Area, error)
 	CreateNewArea(ctx context.Context, areaDTO dto.AreaCreateRequest) (entity.Area, error)
-	GetAllAreas(ctx context.Context) ([]entity.Area, error)
+	GetAllAreas(ctx context.Context, query common.Query) ([]entity.Area, common.Meta, error)
 	GetAreaByID(ctx context.Context, id uint64) (entity.Area, error)
 	UpdateArea(ctx context.Context, areaDTO dto.AreaCreateRequest, area entity.Area) (entity.Area, error)
 	DeleteAreaByID(ctx context.Context, id uint64) error
+	UpdateAreaLayout(ctx context.Context, layout []string, area entity.Area) (entity.Area, error)
 }
 
 func NewAreaService(areaR repository.AreaRepository) AreaService {
@@ -47,12 +50,12 @@ func (areaS *areaService) CreateNewArea(ctx context.Context, areaDTO dto.AreaCre
 	return newArea, nil
 }
 
-func (areaS *areaService) GetAllAreas(ctx context.Context) ([]entity.Area, error) {
-	areas, err := areaS.areaRepository.GetAllAreas(ctx, nil)
+func (areaS *areaService) GetAllAreas(ctx context.Context, query common.Query) ([]entity.Area, common.Meta, error) {
+	areas, total, err := areaS.areaRepository.GetAllAreas(ctx, nil, query)
 	if err != nil {
-		return []entity.Area{}, err
+		return []entity.Area{}, common.Meta{}, err
 	}
-	return areas, nil
+	return areas, query.Meta(total), nil
 }
 
 func (areaS *areaService) GetAreaByID(ctx context.Context, id uint64) (entity.Area, error) {
@@ -64,6 +67,15 @@ func (areaS *areaService) GetAreaByID(ctx context.Context, id uint64) (entity.Ar
 }
 
 func (areaS *areaService) UpdateArea(ctx context.Context, areaDTO dto.AreaCreateRequest, area entity.Area) (entity.Area, error) {
+	// Spot count and spot per row of an area with a layout follow the layout
+	if area.Layout != "" {
+		spots, err := parseAreaLayout(area.Layout)
+		if err != nil {
+			return entity.Area{}, err
+		}
+		areaDTO.SpotCount, areaDTO.SpotPerRow = layoutDimensions(spots)
+	}
+
 	area, err := areaS.areaRepository.UpdateArea(ctx, nil, areaDTO, area)
 	if err != nil {
 		return entity.Area{}, err
@@ -77,4 +89,25 @@ func (areaS *areaService) DeleteAreaByID(ctx context.Context, id uint64) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (areaS *areaService) UpdateAreaLayout(ctx context.Context, layout []string, area entity.Area) (entity.Area, error) {
+	area.Layout = strings.Join(layout, "\n")
+	spots, err := parseAreaLayout(area.Layout)
+	if err != nil {
+		return entity.Area{}, err
+	}
+
+	areaDTO := dto.AreaCreateRequest{
+		Name:              area.Name,
+		BasePrice:         area.BasePrice,
+		TurnaroundMinutes: area.TurnaroundMinutes,
+	}
+	areaDTO.SpotCount, areaDTO.SpotPerRow = layoutDimensions(spots)
+
+	area, err = areaS.areaRepository.UpdateArea(ctx, nil, areaDTO, area)
+	if err != nil {
+		return entity.Area{}, err
+	}
+	return area, nil
+}
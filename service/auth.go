@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fp-rpl/common"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrUserSuspended       = errors.New("account is suspended")
+)
+
+type authService struct {
+	refreshTokenRepository repository.RefreshTokenRepository
+	userRepository         repository.UserRepository
+	roleRepository         repository.RoleRepository
+	jwtService             JWTService
+	refreshTokenTTL        time.Duration
+}
+
+// AuthService issues access and refresh tokens to logged in devices and
+// checks access tokens against the revocation list
+type AuthService interface {
+	IssueTokens(ctx context.Context, user entity.User, deviceID string) (common.AuthResponse, error)
+	RefreshTokens(ctx context.Context, refreshToken string, deviceID string) (common.AuthResponse, error)
+	Logout(ctx context.Context, familyID string) error
+	RevokeUserTokens(ctx context.Context, userID uint64, keepFamilyID string) error
+	ValidateAccessToken(ctx context.Context, token string) (AccessClaims, error)
+	DeleteExpiredTokens(ctx context.Context) (int64, error)
+}
+
+func NewAuthService(refreshTokenR repository.RefreshTokenRepository, userR repository.UserRepository, roleR repository.RoleRepository, jwtS JWTService) AuthService {
+	return &authService{
+		refreshTokenRepository: refreshTokenR,
+		userRepository:         userR,
+		roleRepository:         roleR,
+		jwtService:             jwtS,
+		refreshTokenTTL:        getRefreshTokenTTL(),
+	}
+}
+
+func getRefreshTokenTTL() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// newToken makes up a random token to hand out, of which only the hash made
+// by hashToken is stored
+func newToken() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAccessToken issues an access token carrying the permissions the
+// role of the user has right now
+func (authS *authService) generateAccessToken(ctx context.Context, user entity.User, familyID string) (string, error) {
+	role, err := authS.roleRepository.GetRoleByName(ctx, nil, user.Role)
+	if err != nil {
+		return "", err
+	}
+	return authS.jwtService.GenerateToken(user.ID, user.Role, role.PermissionNames(), familyID), nil
+}
+
+// createRefreshToken stores a new refresh token of a family and returns the
+// token itself, which is never stored
+func (authS *authService) createRefreshToken(ctx context.Context, tx *gorm.DB, userID uint64, familyID string, deviceID string, now time.Time) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = authS.refreshTokenRepository.CreateRefreshToken(ctx, tx, entity.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		FamilyID:  familyID,
+		DeviceID:  deviceID,
+		ExpiresAt: now.Add(authS.refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// IssueTokens starts a new token family for a device the user logged in on.
+// Devices that don't identify themselves are given an id to refresh with.
+func (authS *authService) IssueTokens(ctx context.Context, user entity.User, deviceID string) (common.AuthResponse, error) {
+	if user.IsSuspended() {
+		return common.AuthResponse{}, ErrUserSuspended
+	}
+
+	if deviceID == "" {
+		deviceID = uuid.NewString()
+	}
+
+	familyID := uuid.NewString()
+	token, err := authS.generateAccessToken(ctx, user, familyID)
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+
+	refreshToken, err := authS.createRefreshToken(ctx, nil, user.ID, familyID, deviceID, time.Now())
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+
+	return common.CreateAuthResponse(token, refreshToken, deviceID, user.Role), nil
+}
+
+// RefreshTokens trades a refresh token for a new access token and a new
+// refresh token of the same family. The refresh token must come from the
+// device it was issued to. A refresh token that was already traded in is
+// taken as stolen, so its whole family is revoked.
+func (authS *authService) RefreshTokens(ctx context.Context, refreshToken string, deviceID string) (common.AuthResponse, error) {
+	tx, err := authS.refreshTokenRepository.BeginTx(ctx)
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+
+	storedToken, err := authS.refreshTokenRepository.LockRefreshTokenByHash(ctx, tx, hashToken(refreshToken))
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, err
+	}
+
+	now := time.Now()
+	if reflect.DeepEqual(storedToken, entity.RefreshToken{}) || storedToken.RevokedAt != nil || !now.Before(storedToken.ExpiresAt) || storedToken.DeviceID != deviceID {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, ErrRefreshTokenInvalid
+	}
+
+	if storedToken.UsedAt != nil {
+		err = authS.revokeFamily(ctx, tx, storedToken.FamilyID, now)
+		if err != nil {
+			authS.refreshTokenRepository.RollbackTx(ctx, tx)
+			return common.AuthResponse{}, err
+		}
+
+		err = authS.refreshTokenRepository.CommitTx(ctx, tx)
+		if err != nil {
+			return common.AuthResponse{}, err
+		}
+		return common.AuthResponse{}, errors.New("refresh token has already been used, log in again")
+	}
+
+	user, err := authS.userRepository.GetUserByID(ctx, tx, storedToken.UserID)
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, ErrRefreshTokenInvalid
+	}
+
+	if user.IsSuspended() {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, ErrUserSuspended
+	}
+
+	err = authS.refreshTokenRepository.MarkRefreshTokenUsed(ctx, tx, storedToken.ID, now)
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, err
+	}
+
+	newRefreshToken, err := authS.createRefreshToken(ctx, tx, user.ID, storedToken.FamilyID, deviceID, now)
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, err
+	}
+
+	token, err := authS.generateAccessToken(ctx, user, storedToken.FamilyID)
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return common.AuthResponse{}, err
+	}
+
+	err = authS.refreshTokenRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+
+	return common.CreateAuthResponse(token, newRefreshToken, deviceID, user.Role), nil
+}
+
+// revokeFamily revokes the refresh tokens of a family and lists the family
+// for as long as access tokens issued to it can still be valid
+func (authS *authService) revokeFamily(ctx context.Context, tx *gorm.DB, familyID string, now time.Time) error {
+	err := authS.refreshTokenRepository.RevokeRefreshTokenFamily(ctx, tx, familyID, now)
+	if err != nil {
+		return err
+	}
+
+	return authS.refreshTokenRepository.CreateRevokedToken(ctx, tx, entity.RevokedToken{
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(getAccessTokenTTL()),
+	})
+}
+
+// Logout ends the login of a device, its access and refresh tokens stop
+// working right away
+func (authS *authService) Logout(ctx context.Context, familyID string) error {
+	tx, err := authS.refreshTokenRepository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = authS.revokeFamily(ctx, tx, familyID, time.Now())
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = authS.refreshTokenRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeUserTokens logs a user out of every device, such as when the account
+// is suspended or its password is reset. The login of keepFamilyID is left
+// alone when it is set, so users changing their password stay logged in on
+// the device they did it with.
+func (authS *authService) RevokeUserTokens(ctx context.Context, userID uint64, keepFamilyID string) error {
+	tx, err := authS.refreshTokenRepository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	familyIDs, err := authS.refreshTokenRepository.GetActiveTokenFamilies(ctx, tx, userID, now, now.Add(-getAccessTokenTTL()))
+	if err != nil {
+		authS.refreshTokenRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	for _, familyID := range familyIDs {
+		if familyID == keepFamilyID {
+			continue
+		}
+
+		err = authS.revokeFamily(ctx, tx, familyID, now)
+		if err != nil {
+			authS.refreshTokenRepository.RollbackTx(ctx, tx)
+			return err
+		}
+	}
+
+	err = authS.refreshTokenRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidateAccessToken checks the signature and expiry of an access token,
+// that its family hasn't been revoked and that its user may still log in
+func (authS *authService) ValidateAccessToken(ctx context.Context, token string) (AccessClaims, error) {
+	claims, err := authS.jwtService.GetClaimsByToken(token)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+
+	// Tokens issued before refresh tokens existed have no family
+	if claims.FamilyID == "" {
+		return AccessClaims{}, errors.New("token has no token family")
+	}
+
+	revoked, err := authS.refreshTokenRepository.IsTokenFamilyRevoked(ctx, nil, claims.FamilyID)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if revoked {
+		return AccessClaims{}, errors.New("token has been revoked")
+	}
+
+	// Suspensions apply right away, even when revoking the tokens failed
+	user, err := authS.userRepository.GetUserByID(ctx, nil, claims.UserID)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if reflect.DeepEqual(user, entity.User{}) {
+		return AccessClaims{}, errors.New("token user not found")
+	}
+	if user.IsSuspended() {
+		return AccessClaims{}, ErrUserSuspended
+	}
+	return claims, nil
+}
+
+func (authS *authService) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	return authS.refreshTokenRepository.DeleteExpiredTokens(ctx, nil, time.Now())
+}
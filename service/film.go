@@ -1,16 +1,27 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/copier"
 )
 
 type filmService struct {
-	filmRepository repository.FilmRepository
+	filmRepository   repository.FilmRepository
+	genreRepository  repository.GenreRepository
+	personRepository repository.PersonRepository
+	storage          Storage
 }
 
 type FilmService interface {
@@ -18,20 +29,148 @@ type FilmService interface {
 	GetFilmBySlug(ctx context.Context, slug string) (entity.Film, error)
 	GetFilmByID(ctx context.Context, id uint64) (entity.Film, error)
 	GetFilmDetailBySlug(ctx context.Context, slug string) (entity.Film, error)
-	GetAllFilm(ctx context.Context) ([]entity.Film, error)
+	GetAllFilm(ctx context.Context, query common.Query, filter dto.FilmFilter) ([]entity.Film, common.Meta, error)
 	UpdateFilm(ctx context.Context, filmDTO dto.FilmRegisterRequest, film entity.Film) (entity.Film, error)
 	DeleteFilm(ctx context.Context, slug string) error
 	GetAllFilmByStatus(ctx context.Context, status string) ([]entity.Film, error)
+	SearchFilms(ctx context.Context, search string, query common.Query) ([]entity.Film, common.Meta, error)
+	CheckFilmRelations(ctx context.Context, filmDTO dto.FilmRegisterRequest) error
+	UpdateScheduledStatuses(ctx context.Context) (int, error)
+	UploadFilmPoster(ctx context.Context, film entity.Film, data []byte) (entity.Film, error)
+	DeleteFilmPoster(ctx context.Context, film entity.Film) (entity.Film, error)
+	UploadFilmTrailer(ctx context.Context, film entity.Film, file io.ReadSeeker) (entity.Film, error)
+	DeleteFilmTrailer(ctx context.Context, film entity.Film) (entity.Film, error)
 }
 
-func NewFilmService(filmR repository.FilmRepository) FilmService {
-	return &filmService{filmRepository: filmR}
+func NewFilmService(filmR repository.FilmRepository, genreR repository.GenreRepository, personR repository.PersonRepository, storage Storage) FilmService {
+	return &filmService{filmRepository: filmR, genreRepository: genreR, personRepository: personR, storage: storage}
 }
+
+// filmRelations loads the genres and builds the credits a film request asks
+// for, failing when one of them doesn't exist
+func (fs *filmService) filmRelations(ctx context.Context, filmDTO dto.FilmRegisterRequest) ([]entity.Genre, []entity.FilmCredit, error) {
+	genres, err := fs.genreRepository.GetGenresByIDs(ctx, nil, filmDTO.GenreIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := map[uint64]bool{}
+	for _, genre := range genres {
+		found[genre.ID] = true
+	}
+	for _, id := range filmDTO.GenreIDs {
+		if !found[id] {
+			return nil, nil, errors.New("genre with id " + strconv.FormatUint(id, 10) + " not found")
+		}
+	}
+
+	var personIDs []uint64
+	for _, credit := range filmDTO.Credits {
+		personIDs = append(personIDs, credit.PersonID)
+	}
+	people, err := fs.personRepository.GetPeopleByIDs(ctx, nil, personIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found = map[uint64]bool{}
+	for _, person := range people {
+		found[person.ID] = true
+	}
+
+	credits := []entity.FilmCredit{}
+	billing := map[string]int{}
+	for _, credit := range filmDTO.Credits {
+		if !found[credit.PersonID] {
+			return nil, nil, errors.New("person with id " + strconv.FormatUint(credit.PersonID, 10) + " not found")
+		}
+
+		billing[credit.Role]++
+		credits = append(credits, entity.FilmCredit{
+			PersonID:  credit.PersonID,
+			Role:      credit.Role,
+			Character: strings.TrimSpace(credit.Character),
+			Billing:   billing[credit.Role],
+		})
+	}
+	return genres, credits, nil
+}
+
+// ParseFilmDate reads a YYYY-MM-DD date of a film request, an empty one
+// being no date
+func ParseFilmDate(date string) (*time.Time, error) {
+	if date == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, errors.New("date " + date + " must use the YYYY-MM-DD format")
+	}
+	return &parsed, nil
+}
+
+// applyFilmSchedule copies the release and end dates of a film request onto
+// film and, unless the status is overridden, sets its status from them
+func applyFilmSchedule(film *entity.Film, filmDTO dto.FilmRegisterRequest, now time.Time) error {
+	releaseDate, err := ParseFilmDate(filmDTO.ReleaseDate)
+	if err != nil {
+		return err
+	}
+
+	endDate, err := ParseFilmDate(filmDTO.EndDate)
+	if err != nil {
+		return err
+	}
+
+	film.ReleaseDate = releaseDate
+	film.EndDate = endDate
+	film.StatusOverride = filmDTO.StatusOverride
+	if film.FollowsSchedule() {
+		film.Status = film.ScheduledStatus(now)
+	}
+	return nil
+}
+
+func (fs *filmService) CheckFilmRelations(ctx context.Context, filmDTO dto.FilmRegisterRequest) error {
+	_, _, err := fs.filmRelations(ctx, filmDTO)
+	return err
+}
+
 func (fs *filmService) CreateNewFilm(ctx context.Context, filmDTO dto.FilmRegisterRequest) (entity.Film, error) {
 	var film entity.Film
 	copier.Copy(&film, &filmDTO)
 
-	NewFilm, err := fs.filmRepository.CreateNewFilm(ctx, nil, film)
+	genres, credits, err := fs.filmRelations(ctx, filmDTO)
+	if err != nil {
+		return entity.Film{}, err
+	}
+	film.Genres = genres
+	film.Credits = credits
+
+	err = applyFilmSchedule(&film, filmDTO, time.Now())
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	tx, err := fs.filmRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	NewFilm, err := fs.filmRepository.CreateNewFilm(ctx, tx, film)
+	if err != nil {
+		fs.filmRepository.RollbackTx(ctx, tx)
+		return entity.Film{}, err
+	}
+
+	err = fs.filmRepository.RefreshFilmSearch(ctx, tx, []uint64{NewFilm.ID})
+	if err != nil {
+		fs.filmRepository.RollbackTx(ctx, tx)
+		return entity.Film{}, err
+	}
+
+	err = fs.filmRepository.CommitTx(ctx, tx)
 	if err != nil {
 		return entity.Film{}, err
 	}
@@ -61,12 +200,12 @@ func (fs *filmService) GetFilmDetailBySlug(ctx context.Context, slug string) (en
 	return film, nil
 }
 
-func (fs *filmService) GetAllFilm(ctx context.Context) ([]entity.Film, error) {
-	films, err := fs.filmRepository.GetAllFilms(ctx, nil)
+func (fs *filmService) GetAllFilm(ctx context.Context, query common.Query, filter dto.FilmFilter) ([]entity.Film, common.Meta, error) {
+	films, total, err := fs.filmRepository.GetAllFilms(ctx, nil, query, filter)
 	if err != nil {
-		return []entity.Film{}, err
+		return []entity.Film{}, common.Meta{}, err
 	}
-	return films, nil
+	return films, query.Meta(total), nil
 }
 
 func (fs *filmService) GetAllFilmByStatus(ctx context.Context, status string) ([]entity.Film, error) {
@@ -78,10 +217,87 @@ func (fs *filmService) GetAllFilmByStatus(ctx context.Context, status string) ([
 }
 
 func (fs *filmService) UpdateFilm(ctx context.Context, filmDTO dto.FilmRegisterRequest, film entity.Film) (entity.Film, error) {
-	film, err := fs.filmRepository.UpdateFilmBySlug(ctx, nil, filmDTO,film)
+	genres, credits, err := fs.filmRelations(ctx, filmDTO)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	err = applyFilmSchedule(&film, filmDTO, time.Now())
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	// The repository copies the request over the film, status included
+	if film.FollowsSchedule() {
+		filmDTO.Status = film.Status
+	}
+
+	// Linking another image drops the uploaded poster
+	if filmDTO.Image == "" {
+		filmDTO.Image = film.Image
+	}
+	replacedPoster := ""
+	if filmDTO.Image != film.Image && film.PosterKey != "" {
+		replacedPoster = film.PosterKey
+		film.PosterKey = ""
+		film.Thumbnails = nil
+	}
+
+	// Likewise linking another trailer drops the uploaded one
+	if filmDTO.Trailer == "" {
+		filmDTO.Trailer = film.Trailer
+	}
+	replacedTrailer := ""
+	if filmDTO.Trailer != film.Trailer && film.TrailerKey != "" {
+		replacedTrailer = film.TrailerKey
+		film.TrailerKey = ""
+	}
+
+	tx, err := fs.filmRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	film, err = fs.filmRepository.UpdateFilmBySlug(ctx, tx, filmDTO, film)
 	if err != nil {
+		fs.filmRepository.RollbackTx(ctx, tx)
 		return entity.Film{}, err
 	}
+	film.Genres = nil
+	film.Credits = nil
+
+	// Genres and credits left out of the request stay as they are
+	if filmDTO.GenreIDs != nil {
+		err = fs.filmRepository.ReplaceFilmGenres(ctx, tx, film, genres)
+		if err != nil {
+			fs.filmRepository.RollbackTx(ctx, tx)
+			return entity.Film{}, err
+		}
+		film.Genres = genres
+	}
+
+	if filmDTO.Credits != nil {
+		err = fs.filmRepository.ReplaceFilmCredits(ctx, tx, film, credits)
+		if err != nil {
+			fs.filmRepository.RollbackTx(ctx, tx)
+			return entity.Film{}, err
+		}
+		film.Credits = credits
+	}
+
+	err = fs.filmRepository.RefreshFilmSearch(ctx, tx, []uint64{film.ID})
+	if err != nil {
+		fs.filmRepository.RollbackTx(ctx, tx)
+		return entity.Film{}, err
+	}
+
+	err = fs.filmRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	fs.removeMedia(ctx, posterKeys(replacedPoster))
+	fs.removeMedia(ctx, trailerKeys(replacedTrailer))
 	return film, nil
 }
 
@@ -92,3 +308,168 @@ func (fs *filmService) DeleteFilm(ctx context.Context, slug string) error {
 	}
 	return nil
 }
+
+// SearchFilms ranks films by full-text relevance, and falls back to trigram
+// similarity when nothing matches so a misspelled search still finds films
+func (fs *filmService) SearchFilms(ctx context.Context, search string, query common.Query) ([]entity.Film, common.Meta, error) {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return []entity.Film{}, common.Meta{}, errors.New("search query must not be empty")
+	}
+
+	films, total, err := fs.filmRepository.SearchFilms(ctx, nil, search, query)
+	if err != nil {
+		return []entity.Film{}, common.Meta{}, err
+	}
+
+	if total == 0 {
+		films, total, err = fs.filmRepository.SearchFilmsBySimilarity(ctx, nil, search, query)
+		if err != nil {
+			return []entity.Film{}, common.Meta{}, err
+		}
+	}
+	return films, query.Meta(total), nil
+}
+
+// UpdateScheduledStatuses moves films following their release and end dates
+// to the status those give them now, returning how many films changed
+func (fs *filmService) UpdateScheduledStatuses(ctx context.Context) (int, error) {
+	films, err := fs.filmRepository.GetScheduledFilms(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	now := time.Now()
+	for _, film := range films {
+		status := film.ScheduledStatus(now)
+		if status == film.Status {
+			continue
+		}
+
+		err = fs.filmRepository.UpdateFilmStatus(ctx, nil, film.ID, status)
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// UploadFilmPoster stores a poster and its thumbnails and links the film to
+// them, removing the poster it replaces
+func (fs *filmService) UploadFilmPoster(ctx context.Context, film entity.Film, data []byte) (entity.Film, error) {
+	files, err := posterFiles(film.ID, data)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	oldKey := film.PosterKey
+	newKey := files[0].key
+	for _, file := range files {
+		err = fs.storage.Put(ctx, file.key, file.contentType, bytes.NewReader(file.data))
+		if err != nil {
+			if newKey != oldKey {
+				fs.removeMedia(ctx, posterKeys(newKey))
+			}
+			return entity.Film{}, errors.New("failed to store poster")
+		}
+	}
+
+	film.PosterKey = newKey
+	film.Image = MediaURL(newKey)
+	film.Thumbnails = posterThumbnails(newKey)
+	film, err = fs.filmRepository.UpdateFilmPoster(ctx, nil, film)
+	if err != nil {
+		if newKey != oldKey {
+			fs.removeMedia(ctx, posterKeys(newKey))
+		}
+		return entity.Film{}, err
+	}
+
+	if newKey != oldKey {
+		fs.removeMedia(ctx, posterKeys(oldKey))
+	}
+	return film, nil
+}
+
+// DeleteFilmPoster unlinks the uploaded poster of a film and removes its files
+func (fs *filmService) DeleteFilmPoster(ctx context.Context, film entity.Film) (entity.Film, error) {
+	if film.PosterKey == "" {
+		return entity.Film{}, errors.New("film has no uploaded poster")
+	}
+
+	oldKey := film.PosterKey
+	film.PosterKey = ""
+	film.Image = ""
+	film.Thumbnails = nil
+	film, err := fs.filmRepository.UpdateFilmPoster(ctx, nil, film)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	fs.removeMedia(ctx, posterKeys(oldKey))
+	return film, nil
+}
+
+// UploadFilmTrailer stores a trailer and links the film to it, removing the
+// trailer it replaces
+func (fs *filmService) UploadFilmTrailer(ctx context.Context, film entity.Film, file io.ReadSeeker) (entity.Film, error) {
+	newKey, contentType, err := trailerFile(film.ID, file)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	oldKey := film.TrailerKey
+	err = fs.storage.Put(ctx, newKey, contentType, file)
+	if err != nil {
+		if newKey != oldKey {
+			fs.removeMedia(ctx, trailerKeys(newKey))
+		}
+		return entity.Film{}, errors.New("failed to store trailer")
+	}
+
+	film.TrailerKey = newKey
+	film.Trailer = MediaURL(newKey)
+	film, err = fs.filmRepository.UpdateFilmTrailer(ctx, nil, film)
+	if err != nil {
+		if newKey != oldKey {
+			fs.removeMedia(ctx, trailerKeys(newKey))
+		}
+		return entity.Film{}, err
+	}
+
+	if newKey != oldKey {
+		fs.removeMedia(ctx, trailerKeys(oldKey))
+	}
+	return film, nil
+}
+
+// DeleteFilmTrailer unlinks the uploaded trailer of a film and removes its file
+func (fs *filmService) DeleteFilmTrailer(ctx context.Context, film entity.Film) (entity.Film, error) {
+	if film.TrailerKey == "" {
+		return entity.Film{}, errors.New("film has no uploaded trailer")
+	}
+
+	oldKey := film.TrailerKey
+	film.TrailerKey = ""
+	film.Trailer = ""
+	film, err := fs.filmRepository.UpdateFilmTrailer(ctx, nil, film)
+	if err != nil {
+		return entity.Film{}, err
+	}
+
+	fs.removeMedia(ctx, trailerKeys(oldKey))
+	return film, nil
+}
+
+// removeMedia deletes files that are no longer linked to. A file that fails
+// to be deleted is only left behind, so the error is logged and dropped.
+func (fs *filmService) removeMedia(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		err := fs.storage.Delete(ctx, key)
+		if err != nil {
+			log.Println("failed to remove media " + key + ": " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"fp-rpl/utils"
+	"strings"
+)
+
+type genreService struct {
+	genreRepository repository.GenreRepository
+	filmRepository  repository.FilmRepository
+}
+
+type GenreService interface {
+	CreateNewGenre(ctx context.Context, genreDTO dto.GenreCreateRequest) (entity.Genre, error)
+	GetAllGenres(ctx context.Context) ([]entity.Genre, error)
+	GetGenreByID(ctx context.Context, id uint64) (entity.Genre, error)
+	GetGenreBySlug(ctx context.Context, slug string) (entity.Genre, error)
+	UpdateGenre(ctx context.Context, genreDTO dto.GenreCreateRequest, genre entity.Genre) (entity.Genre, error)
+	DeleteGenreByID(ctx context.Context, id uint64) error
+}
+
+func NewGenreService(genreR repository.GenreRepository, filmR repository.FilmRepository) GenreService {
+	return &genreService{genreRepository: genreR, filmRepository: filmR}
+}
+
+func (genreS *genreService) CreateNewGenre(ctx context.Context, genreDTO dto.GenreCreateRequest) (entity.Genre, error) {
+	name := strings.TrimSpace(genreDTO.Name)
+	genre := entity.Genre{Name: name, Slug: utils.Slugify(name)}
+
+	newGenre, err := genreS.genreRepository.CreateNewGenre(ctx, nil, genre)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	return newGenre, nil
+}
+
+func (genreS *genreService) GetAllGenres(ctx context.Context) ([]entity.Genre, error) {
+	genres, err := genreS.genreRepository.GetAllGenres(ctx, nil)
+	if err != nil {
+		return []entity.Genre{}, err
+	}
+	return genres, nil
+}
+
+func (genreS *genreService) GetGenreByID(ctx context.Context, id uint64) (entity.Genre, error) {
+	genre, err := genreS.genreRepository.GetGenreByID(ctx, nil, id)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	return genre, nil
+}
+
+func (genreS *genreService) GetGenreBySlug(ctx context.Context, slug string) (entity.Genre, error) {
+	genre, err := genreS.genreRepository.GetGenreBySlug(ctx, nil, slug)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	return genre, nil
+}
+
+// UpdateGenre renames a genre, which also changes what its films are found by
+func (genreS *genreService) UpdateGenre(ctx context.Context, genreDTO dto.GenreCreateRequest, genre entity.Genre) (entity.Genre, error) {
+	genre.Name = strings.TrimSpace(genreDTO.Name)
+	genre.Slug = utils.Slugify(genre.Name)
+
+	tx, err := genreS.genreRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+
+	genre, err = genreS.genreRepository.UpdateGenre(ctx, tx, genre)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return entity.Genre{}, err
+	}
+
+	filmIDs, err := genreS.genreRepository.GetFilmIDsByGenreID(ctx, tx, genre.ID)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return entity.Genre{}, err
+	}
+
+	err = genreS.filmRepository.RefreshFilmSearch(ctx, tx, filmIDs)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return entity.Genre{}, err
+	}
+
+	err = genreS.genreRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Genre{}, err
+	}
+	return genre, nil
+}
+
+func (genreS *genreService) DeleteGenreByID(ctx context.Context, id uint64) error {
+	tx, err := genreS.genreRepository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	filmIDs, err := genreS.genreRepository.GetFilmIDsByGenreID(ctx, tx, id)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = genreS.genreRepository.DeleteGenreByID(ctx, tx, id)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = genreS.filmRepository.RefreshFilmSearch(ctx, tx, filmIDs)
+	if err != nil {
+		genreS.genreRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	return genreS.genreRepository.CommitTx(ctx, tx)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -8,66 +9,146 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTService interface {
-	GenerateToken(teamID uint64, role string) string
+	GenerateToken(teamID uint64, role string, permissions []string, familyID string) string
 	ValidateToken(token string) (*jwt.Token, error)
 	GetIDByToken(token string) (uint64, error)
 	GetRoleByToken(token string) (string, error)
+	GetClaimsByToken(token string) (AccessClaims, error)
+}
+
+// AccessClaims are what an access token says about its holder. Permissions
+// are those of the role of the holder when the token was issued. FamilyID is
+// the refresh token family the access token was issued with.
+type AccessClaims struct {
+	UserID      uint64
+	Role        string
+	Permissions []string
+	FamilyID    string
+}
+
+func (c AccessClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type jwtCustomClaim struct {
-	ID   uint64 `json:"id"`
-	Role string `json:"role"`
+	ID          uint64   `json:"id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	FamilyID    string   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 type jwtService struct {
-	secretKey string
-	issuer    string
+	keys       map[string]jwtKey
+	signingKey jwtKey
+	methods    []string
+	issuer     string
+	audience   string
 }
 
-func NewJWTService() JWTService {
-	return &jwtService{
-		secretKey: getSecretKey(),
-		issuer:    "admin",
+// NewJWTService loads the signing keys, failing when they are missing or
+// weak in production
+func NewJWTService() (JWTService, error) {
+	keys, err := loadJWTKeys()
+	if err != nil {
+		return nil, err
 	}
+
+	j := &jwtService{
+		keys:       map[string]jwtKey{},
+		signingKey: keys[0],
+		issuer:     getEnvOrDefault("JWT_ISSUER", "fp-rpl"),
+		audience:   getEnvOrDefault("JWT_AUDIENCE", "fp-rpl"),
+	}
+	for _, key := range keys {
+		j.keys[key.id] = key
+		j.methods = append(j.methods, key.method.Alg())
+	}
+	return j, nil
 }
 
-func getSecretKey() string {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "jwt_secret_key"
+func getEnvOrDefault(name string, value string) string {
+	if os.Getenv(name) != "" {
+		return os.Getenv(name)
 	}
-	return secretKey
+	return value
 }
 
-func (j *jwtService) GenerateToken(id uint64, role string) string {
+// getAccessTokenTTL returns how long access tokens last. They are short
+// lived since refresh tokens renew them.
+func getAccessTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (j *jwtService) GenerateToken(id uint64, role string, permissions []string, familyID string) string {
 	claims := &jwtCustomClaim{
 		id,
 		role,
+		permissions,
+		familyID,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 120)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(getAccessTokenTTL())),
 			Issuer:    j.issuer,
+			Audience:  jwt.ClaimStrings{j.audience},
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(j.secretKey))
+	token := jwt.NewWithClaims(j.signingKey.method, claims)
+	token.Header["kid"] = j.signingKey.id
+	t, err := token.SignedString(j.signingKey.signKey)
 	if err != nil {
 		log.Println(err)
 	}
 	return t
 }
 
+// keyFunc picks the key a token was signed with by its kid header, and
+// refuses tokens signed with another algorithm than that of the key
+func (j *jwtService) keyFunc(t_ *jwt.Token) (any, error) {
+	kid, _ := t_.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %v", t_.Header["kid"])
+	}
+	if t_.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", t_.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+func (j *jwtService) parserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods(j.methods),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(j.audience),
+	}
+}
+
+// ValidateToken checks the signature, issuer, audience and expiry of a token.
+// Tokens without an expiry are refused.
 func (j *jwtService) ValidateToken(token string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(t_ *jwt.Token) (any, error) {
-		if _, ok := t_.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method %v", t_.Header["alg"])
-		}
-		return []byte(j.secretKey), nil
-	})
+	t_Token, err := jwt.Parse(token, j.keyFunc, j.parserOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	if exp, err := t_Token.Claims.GetExpirationTime(); err != nil || exp == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	return t_Token, nil
 }
 
 func (j *jwtService) GetIDByToken(token string) (uint64, error) {
@@ -90,3 +171,15 @@ func (j *jwtService) GetRoleByToken(token string) (string, error) {
 	role := fmt.Sprintf("%v", claims["role"])
 	return role, nil
 }
+
+func (j *jwtService) GetClaimsByToken(token string) (AccessClaims, error) {
+	claims := &jwtCustomClaim{}
+	t_Token, err := jwt.ParseWithClaims(token, claims, j.keyFunc, j.parserOptions()...)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if !t_Token.Valid || claims.ExpiresAt == nil {
+		return AccessClaims{}, errors.New("token is invalid")
+	}
+	return AccessClaims{UserID: claims.ID, Role: claims.Role, Permissions: claims.Permissions, FamilyID: claims.FamilyID}, nil
+}
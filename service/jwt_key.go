@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretLength is the shortest HS256 secret accepted in production, the
+// size of the SHA-256 output
+const minSecretLength = 32
+
+// jwtKey is a key tokens are signed or verified with, told apart by the kid
+// header of the token. signKey is nil for keys that only verify tokens.
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// loadJWTKeys reads the keys tokens are signed and verified with. JWT_KEYS
+// lists them as kid:alg:source entries separated by commas, alg being HS256,
+// RS256 or EdDSA and source the secret for HS256 or the path of a PEM file
+// otherwise. The first key signs new tokens. The others only verify tokens
+// signed before a rotation, and can be public keys. Without JWT_KEYS the
+// HS256 secret of JWT_SECRET is used.
+//
+// In production a missing or weak key stops the server from starting.
+// Elsewhere a random secret is made up when none is set, so tokens do not
+// outlive a restart.
+func loadJWTKeys() ([]jwtKey, error) {
+	production := os.Getenv("APP_ENV") == "production"
+
+	var entries []string
+	if os.Getenv("JWT_KEYS") != "" {
+		entries = strings.Split(os.Getenv("JWT_KEYS"), ",")
+	} else if os.Getenv("JWT_SECRET") != "" {
+		entries = []string{"default:HS256:" + os.Getenv("JWT_SECRET")}
+	} else if production {
+		return nil, errors.New("JWT_KEYS or JWT_SECRET must be set in production")
+	} else {
+		log.Println("jwt: JWT_KEYS and JWT_SECRET are not set, using a random secret")
+		secret := make([]byte, minSecretLength)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+		return []jwtKey{{id: "dev", method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}}, nil
+	}
+
+	var keys []jwtKey
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, errors.New("jwt key entries must look like kid:alg:source")
+		}
+		if seen[parts[0]] {
+			return nil, errors.New("jwt key " + parts[0] + " is listed twice")
+		}
+		seen[parts[0]] = true
+
+		key, err := parseJWTKey(parts[0], parts[1], parts[2], production)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if keys[0].signKey == nil {
+		return nil, errors.New("jwt key " + keys[0].id + " signs tokens so it must be a secret or private key")
+	}
+	return keys, nil
+}
+
+func parseJWTKey(id string, alg string, source string, production bool) (jwtKey, error) {
+	switch alg {
+	case "HS256":
+		if len(source) < minSecretLength || source == "jwt_secret_key" {
+			if production {
+				return jwtKey{}, errors.New("jwt key " + id + " must be a secret of at least 32 characters")
+			}
+			log.Println("jwt: key " + id + " is a weak secret, it would be refused in production")
+		}
+		return jwtKey{id: id, method: jwt.SigningMethodHS256, signKey: []byte(source), verifyKey: []byte(source)}, nil
+
+	case "RS256":
+		pem, err := os.ReadFile(source)
+		if err != nil {
+			return jwtKey{}, errors.New("failed to read jwt key " + id + ": " + err.Error())
+		}
+
+		key := jwtKey{id: id, method: jwt.SigningMethodRS256}
+		if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		} else if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+			key.verifyKey = publicKey
+		} else {
+			return jwtKey{}, errors.New("jwt key " + id + " is not an RSA key in PEM format")
+		}
+
+		if key.verifyKey.(*rsa.PublicKey).N.BitLen() < 2048 {
+			return jwtKey{}, errors.New("jwt key " + id + " must be an RSA key of at least 2048 bits")
+		}
+		return key, nil
+
+	case "EdDSA":
+		pem, err := os.ReadFile(source)
+		if err != nil {
+			return jwtKey{}, errors.New("failed to read jwt key " + id + ": " + err.Error())
+		}
+
+		key := jwtKey{id: id, method: jwt.SigningMethodEdDSA}
+		if privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			key.signKey = privateKey
+			key.verifyKey = privateKey.(crypto.Signer).Public()
+		} else if publicKey, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+			key.verifyKey = publicKey
+		} else {
+			return jwtKey{}, errors.New("jwt key " + id + " is not an Ed25519 key in PEM format")
+		}
+
+		if _, ok := key.verifyKey.(ed25519.PublicKey); !ok {
+			return jwtKey{}, errors.New("jwt key " + id + " is not an Ed25519 key in PEM format")
+		}
+		return key, nil
+	}
+	return jwtKey{}, errors.New("jwt key " + id + " uses unsupported algorithm " + alg + ", use HS256, RS256 or EdDSA")
+}
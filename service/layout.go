@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"fp-rpl/entity"
+	"fp-rpl/utils"
+	"strings"
+)
+
+// An area layout is a grid with one line per row of the auditorium and one
+// character per position:
+//
+//	S  regular seat
+//	V  VIP seat
+//	C  couple seat
+//	W  wheelchair space
+//	X  seat that can't be sold
+//	.  aisle or gap (a blank works too)
+//
+// Lines holding at least one seat get row letters from A onwards and their
+// seats are numbered from 1, left to right, skipping gaps.
+var layoutSpotTypes = map[rune]string{
+	'S': entity.SpotRegular,
+	'V': entity.SpotVIP,
+	'C': entity.SpotCouple,
+	'W': entity.SpotWheelchair,
+	'X': entity.SpotRegular,
+}
+
+const maxLayoutRows = 26
+
+// parseAreaLayout turns a layout into the spots of a single session
+func parseAreaLayout(layout string) ([]entity.Spot, error) {
+	var spots []entity.Spot
+	rowCount := 0
+	for i, line := range strings.Split(layout, "\n") {
+		number := 0
+		for j, char := range strings.TrimRight(line, "\r") {
+			if char == '.' || char == ' ' {
+				continue
+			}
+
+			spotType, ok := layoutSpotTypes[char]
+			if !ok {
+				return nil, errors.New("layout contains unknown character " + string(char))
+			}
+
+			if number == 0 {
+				rowCount++
+				if rowCount > maxLayoutRows {
+					return nil, errors.New("layout has too many rows")
+				}
+			}
+			number++
+
+			spots = append(spots, entity.Spot{
+				Row:        string(utils.IntToChar(rowCount)),
+				Number:     number,
+				Type:       spotType,
+				IsBlocked:  char == 'X',
+				GridRow:    i + 1,
+				GridColumn: j + 1,
+			})
+		}
+	}
+
+	if len(spots) == 0 {
+		return nil, errors.New("layout has no seat")
+	}
+	return spots, nil
+}
+
+// buildAreaSpots returns the spots every session in the area starts with,
+// taken from the area layout or, without one, a full rectangle of regular seats
+func buildAreaSpots(area entity.Area) ([]entity.Spot, error) {
+	if area.Layout != "" {
+		return parseAreaLayout(area.Layout)
+	}
+
+	var spots []entity.Spot
+	rowCount := area.SpotCount / area.SpotPerRow
+	for i := 1; i <= rowCount; i++ {
+		for j := 1; j <= area.SpotPerRow; j++ {
+			spots = append(spots, entity.Spot{
+				Row:        string(utils.IntToChar(i)),
+				Number:     j,
+				Type:       entity.SpotRegular,
+				GridRow:    i,
+				GridColumn: j,
+			})
+		}
+	}
+	return spots, nil
+}
+
+// layoutDimensions returns the seat count and the widest row of a layout
+func layoutDimensions(spots []entity.Spot) (int, int) {
+	spotPerRow := 0
+	for _, spot := range spots {
+		if spot.GridColumn > spotPerRow {
+			spotPerRow = spot.GridColumn
+		}
+	}
+	return len(spots), spotPerRow
+}
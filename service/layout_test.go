@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fp-rpl/entity"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAreaLayout(t *testing.T) {
+	spots, err := parseAreaLayout("SS.V\r\n\n.WX\nCC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []entity.Spot{
+		{Row: "A", Number: 1, Type: entity.SpotRegular, GridRow: 1, GridColumn: 1},
+		{Row: "A", Number: 2, Type: entity.SpotRegular, GridRow: 1, GridColumn: 2},
+		{Row: "A", Number: 3, Type: entity.SpotVIP, GridRow: 1, GridColumn: 4},
+		{Row: "B", Number: 1, Type: entity.SpotWheelchair, GridRow: 3, GridColumn: 2},
+		{Row: "B", Number: 2, Type: entity.SpotRegular, IsBlocked: true, GridRow: 3, GridColumn: 3},
+		{Row: "C", Number: 1, Type: entity.SpotCouple, GridRow: 4, GridColumn: 1},
+		{Row: "C", Number: 2, Type: entity.SpotCouple, GridRow: 4, GridColumn: 2},
+	}
+	if !reflect.DeepEqual(spots, want) {
+		t.Errorf("parseAreaLayout() = %+v, want %+v", spots, want)
+	}
+}
+
+func TestParseAreaLayoutLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		valid  bool
+	}{
+		{"most rows", strings.Repeat("S\n", maxLayoutRows), true},
+		{"too many rows", strings.Repeat("S\n", maxLayoutRows+1), false},
+		{"empty lines don't count as rows", strings.Repeat("S\n\n", maxLayoutRows), true},
+		{"unknown character", "SS\nSZ", false},
+		{"lower case seat", "s", false},
+		{"empty", "", false},
+		{"only gaps", "...\n  .\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseAreaLayout(test.layout)
+			if (err == nil) != test.valid {
+				t.Errorf("parseAreaLayout() error = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mail is a plain text email
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// NewMailer returns the mailer chosen by MAIL_DRIVER. With smtp, mails are
+// sent through SMTP_HOST. With file, they are written to MAIL_DIR to be read
+// while testing locally. Otherwise they are only logged, which the log then
+// holds password reset tokens for. In production the server must not start
+// unless mails go out through a fully set up SMTP server.
+func NewMailer() (Mailer, error) {
+	production := os.Getenv("APP_ENV") == "production"
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		config := SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvOrDefault("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+		if production && (config.Host == "" || config.From == "" || (config.Username != "" && config.Password == "")) {
+			return nil, errors.New("SMTP_HOST and MAIL_FROM, and SMTP_PASSWORD along with SMTP_USERNAME, must be set in production")
+		}
+		return NewSMTPMailer(config), nil
+	case "file", "":
+		if production {
+			return nil, errors.New("MAIL_DRIVER must be smtp in production")
+		}
+		if driver == "file" {
+			return NewFileMailer(getEnvOrDefault("MAIL_DIR", "mails")), nil
+		}
+		return NewLogMailer(), nil
+	default:
+		return nil, errors.New("MAIL_DRIVER " + driver + " is not supported")
+	}
+}
+
+// mailMessage formats a mail with its headers. Line breaks are left out of
+// header values so they can't add headers of their own.
+func mailMessage(from string, mail Mail) string {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var message strings.Builder
+	if from != "" {
+		message.WriteString("From: " + header.Replace(from) + "\r\n")
+	}
+	message.WriteString("To: " + header.Replace(mail.To) + "\r\n")
+	message.WriteString("Subject: " + header.Replace(mail.Subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return message.String()
+}
+
+// logMailer writes mails to the server log
+type logMailer struct{}
+
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, mail Mail) error {
+	log.Printf("mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
+
+// fileMailer writes each mail to a file of its own in a directory
+type fileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) Mailer {
+	return &fileMailer{dir: dir}
+}
+
+func (m *fileMailer) Send(ctx context.Context, mail Mail) error {
+	err := os.MkdirAll(m.dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(m.dir, time.Now().Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(mailMessage("", mail))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("mailer: mail to %s written to %s", mail.To, file.Name())
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// smtpMailer sends mails through an SMTP server, authenticating when a
+// username is set
+type smtpMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) Mailer {
+	return &smtpMailer{config: config}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, mail Mail) error {
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	err := smtp.SendMail(addr, auth, m.config.From, []string{mail.To}, []byte(mailMessage(m.config.From, mail)))
+	if err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", mail.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+type passwordResetService struct {
+	passwordResetTokenRepository repository.PasswordResetTokenRepository
+	userRepository               repository.UserRepository
+	mailer                       Mailer
+	resetURL                     string
+	resetTokenTTL                time.Duration
+}
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a link mailed to them
+type PasswordResetService interface {
+	RequestPasswordReset(ctx context.Context, identifier string)
+	ResetPassword(ctx context.Context, resetDTO dto.UserRecoverPasswordRequest) (entity.User, error)
+	DeleteExpiredPasswordResetTokens(ctx context.Context) (int64, error)
+}
+
+func NewPasswordResetService(passwordResetTokenR repository.PasswordResetTokenRepository, userR repository.UserRepository, mailer Mailer) PasswordResetService {
+	return &passwordResetService{
+		passwordResetTokenRepository: passwordResetTokenR,
+		userRepository:               userR,
+		mailer:                       mailer,
+		resetURL:                     getEnvOrDefault("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		resetTokenTTL:                getPasswordResetTokenTTL(),
+	}
+}
+
+func getPasswordResetTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// resetLink is the page of the frontend the token is handed to
+func (resetS *passwordResetService) resetLink(token string) string {
+	if strings.Contains(resetS.resetURL, "?") {
+		return resetS.resetURL + "&token=" + token
+	}
+	return resetS.resetURL + "?token=" + token
+}
+
+// RequestPasswordReset mails a reset link to the user with the given
+// username or email. It returns right away and does the work in the
+// background, logging failures, so neither the answer nor how long it takes
+// tells whether such a user exists.
+func (resetS *passwordResetService) RequestPasswordReset(ctx context.Context, identifier string) {
+	go func() {
+		// The request is done by the time the mail is sent
+		err := resetS.sendPasswordReset(context.Background(), identifier)
+		if err != nil {
+			log.Println("failed to send password reset: " + err.Error())
+		}
+	}()
+}
+
+// sendPasswordReset mails a reset link to the user with the given username
+// or email, doing nothing when there is no such user
+func (resetS *passwordResetService) sendPasswordReset(ctx context.Context, identifier string) error {
+	user, err := resetS.userRepository.GetUserByIdentifier(ctx, nil, identifier, identifier)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) || user.IsSuspended() {
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	tx, err := resetS.passwordResetTokenRepository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Only the latest link sent works
+	now := time.Now()
+	err = resetS.passwordResetTokenRepository.UsePasswordResetTokensOfUser(ctx, tx, user.ID, now)
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	_, err = resetS.passwordResetTokenRepository.CreatePasswordResetToken(ctx, tx, entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(resetS.resetTokenTTL),
+	})
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = resetS.passwordResetTokenRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return resetS.mailer.Send(ctx, Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Hello " + user.Name + ",\n\n" +
+			"We were asked to reset the password of your account " + user.Username + ". " +
+			"Open the link below within " + strconv.Itoa(int(resetS.resetTokenTTL.Minutes())) + " minutes to choose a new password:\n\n" +
+			resetS.resetLink(token) + "\n\n" +
+			"If you didn't ask for it, you can ignore this email and your password stays as it is.\n",
+	})
+}
+
+// ResetPassword sets the password of the user a reset token was mailed to.
+// The token can't be used again afterwards. The tokens the user already has
+// must be revoked as well.
+func (resetS *passwordResetService) ResetPassword(ctx context.Context, resetDTO dto.UserRecoverPasswordRequest) (entity.User, error) {
+	tx, err := resetS.passwordResetTokenRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	resetToken, err := resetS.passwordResetTokenRepository.LockPasswordResetTokenByHash(ctx, tx, hashToken(resetDTO.Token))
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, err
+	}
+
+	now := time.Now()
+	if reflect.DeepEqual(resetToken, entity.PasswordResetToken{}) || resetToken.UsedAt != nil || !now.Before(resetToken.ExpiresAt) {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, ErrPasswordResetTokenInvalid
+	}
+
+	user, err := resetS.userRepository.GetUserByID(ctx, tx, resetToken.UserID)
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) || user.IsSuspended() {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, ErrPasswordResetTokenInvalid
+	}
+
+	user, err = resetS.userRepository.UpdatePasswordUser(ctx, tx, resetDTO.Password, user)
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, err
+	}
+
+	err = resetS.passwordResetTokenRepository.UsePasswordResetTokensOfUser(ctx, tx, user.ID, now)
+	if err != nil {
+		resetS.passwordResetTokenRepository.RollbackTx(ctx, tx)
+		return entity.User{}, err
+	}
+
+	err = resetS.passwordResetTokenRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+func (resetS *passwordResetService) DeleteExpiredPasswordResetTokens(ctx context.Context) (int64, error) {
+	return resetS.passwordResetTokenRepository.DeleteExpiredPasswordResetTokens(ctx, nil, time.Now())
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/entity"
+	"os"
+)
+
+// ErrPaymentDeclined is returned by gateways when the provider refused a
+// charge for good. Other errors leave it unknown whether money moved.
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// PaymentGateway charges and refunds transactions through a payment provider.
+// Both must send the PaymentKey of the transaction as idempotency key, so
+// that retrying after an unknown outcome never moves money twice.
+type PaymentGateway interface {
+	Charge(ctx context.Context, transaction entity.Transaction) (string, error)
+	Refund(ctx context.Context, transaction entity.Transaction) error
+}
+
+// NewPaymentGateway returns the gateway chosen by PAYMENT_DRIVER. Only the
+// fake gateway exists so far, which is used when no driver is set. In
+// production the server doesn't start with it unless PAYMENT_ALLOW_FAKE is
+// true, for deployments that take payments outside the app on purpose.
+func NewPaymentGateway() (PaymentGateway, error) {
+	driver := os.Getenv("PAYMENT_DRIVER")
+	switch driver {
+	case "", "fake":
+		if os.Getenv("APP_ENV") == "production" && os.Getenv("PAYMENT_ALLOW_FAKE") != "true" {
+			return nil, errors.New("the fake payment gateway accepts every payment, set PAYMENT_ALLOW_FAKE to true to use it in production")
+		}
+		return NewFakePaymentGateway(), nil
+	}
+	return nil, errors.New("PAYMENT_DRIVER " + driver + " is not supported")
+}
+
+// fakePaymentGateway accepts every payment without talking to a provider, for local development and testing
+type fakePaymentGateway struct{}
+
+func NewFakePaymentGateway() PaymentGateway {
+	return &fakePaymentGateway{}
+}
+
+func (fakePaymentGateway) Charge(ctx context.Context, transaction entity.Transaction) (string, error) {
+	if transaction.TotalPrice.IsNegative() {
+		return "", ErrPaymentDeclined
+	}
+	return "FAKE-" + transaction.PaymentKey, nil
+}
+
+func (fakePaymentGateway) Refund(ctx context.Context, transaction entity.Transaction) error {
+	if transaction.TotalPrice.IsNegative() {
+		return errors.New("refund amount invalid")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fp-rpl/common"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"fp-rpl/utils"
+	"strings"
+)
+
+type personService struct {
+	personRepository repository.PersonRepository
+	filmRepository   repository.FilmRepository
+}
+
+type PersonService interface {
+	CreateNewPerson(ctx context.Context, personDTO dto.PersonCreateRequest) (entity.Person, error)
+	GetAllPeople(ctx context.Context, query common.Query) ([]entity.Person, common.Meta, error)
+	GetPersonByID(ctx context.Context, id uint64) (entity.Person, error)
+	GetPersonDetailByID(ctx context.Context, id uint64) (entity.Person, error)
+	GetPersonBySlug(ctx context.Context, slug string) (entity.Person, error)
+	UpdatePerson(ctx context.Context, personDTO dto.PersonCreateRequest, person entity.Person) (entity.Person, error)
+	DeletePersonByID(ctx context.Context, id uint64) error
+}
+
+func NewPersonService(personR repository.PersonRepository, filmR repository.FilmRepository) PersonService {
+	return &personService{personRepository: personR, filmRepository: filmR}
+}
+
+// PersonSlug returns the slug a person request asks for, made from the name
+// when it has none
+func PersonSlug(personDTO dto.PersonCreateRequest) string {
+	if strings.TrimSpace(personDTO.Slug) != "" {
+		return utils.Slugify(personDTO.Slug)
+	}
+	return utils.Slugify(personDTO.Name)
+}
+
+func (personS *personService) CreateNewPerson(ctx context.Context, personDTO dto.PersonCreateRequest) (entity.Person, error) {
+	person := entity.Person{
+		Name:      strings.TrimSpace(personDTO.Name),
+		Slug:      PersonSlug(personDTO),
+		Biography: strings.TrimSpace(personDTO.Biography),
+	}
+
+	newPerson, err := personS.personRepository.CreateNewPerson(ctx, nil, person)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return newPerson, nil
+}
+
+func (personS *personService) GetAllPeople(ctx context.Context, query common.Query) ([]entity.Person, common.Meta, error) {
+	people, total, err := personS.personRepository.GetAllPeople(ctx, nil, query)
+	if err != nil {
+		return []entity.Person{}, common.Meta{}, err
+	}
+	return people, query.Meta(total), nil
+}
+
+func (personS *personService) GetPersonByID(ctx context.Context, id uint64) (entity.Person, error) {
+	person, err := personS.personRepository.GetPersonByID(ctx, nil, id)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return person, nil
+}
+
+func (personS *personService) GetPersonDetailByID(ctx context.Context, id uint64) (entity.Person, error) {
+	person, err := personS.personRepository.GetPersonDetailByID(ctx, nil, id)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return person, nil
+}
+
+func (personS *personService) GetPersonBySlug(ctx context.Context, slug string) (entity.Person, error) {
+	person, err := personS.personRepository.GetPersonBySlug(ctx, nil, slug)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return person, nil
+}
+
+// UpdatePerson also refreshes the search of the films the person is in, as
+// films are found by the names of their cast and director
+func (personS *personService) UpdatePerson(ctx context.Context, personDTO dto.PersonCreateRequest, person entity.Person) (entity.Person, error) {
+	person.Name = strings.TrimSpace(personDTO.Name)
+	person.Slug = PersonSlug(personDTO)
+	person.Biography = strings.TrimSpace(personDTO.Biography)
+
+	tx, err := personS.personRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Person{}, err
+	}
+
+	person, err = personS.personRepository.UpdatePerson(ctx, tx, person)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return entity.Person{}, err
+	}
+
+	filmIDs, err := personS.personRepository.GetFilmIDsByPersonID(ctx, tx, person.ID)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return entity.Person{}, err
+	}
+
+	err = personS.filmRepository.RefreshFilmSearch(ctx, tx, filmIDs)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return entity.Person{}, err
+	}
+
+	err = personS.personRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Person{}, err
+	}
+	return person, nil
+}
+
+func (personS *personService) DeletePersonByID(ctx context.Context, id uint64) error {
+	tx, err := personS.personRepository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	filmIDs, err := personS.personRepository.GetFilmIDsByPersonID(ctx, tx, id)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = personS.personRepository.DeletePersonByID(ctx, tx, id)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	err = personS.filmRepository.RefreshFilmSearch(ctx, tx, filmIDs)
+	if err != nil {
+		personS.personRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	return personS.personRepository.CommitTx(ctx, tx)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fp-rpl/utils"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxPosterSize is the largest poster file that can be uploaded, in bytes
+	MaxPosterSize = 5 << 20
+	// maxPosterPixels keeps small files that decode into huge images out
+	maxPosterPixels = 40_000_000
+)
+
+// posterTypes maps the accepted poster content types to their extension
+var posterTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// posterSizes are the widths thumbnails of a poster are made in
+var posterSizes = []struct {
+	name  string
+	width int
+}{
+	{"small", 185},
+	{"medium", 342},
+	{"large", 780},
+}
+
+// posterFile is a file of an uploaded poster waiting to be stored
+type posterFile struct {
+	key         string
+	contentType string
+	data        []byte
+}
+
+// MediaURL returns the address a stored file is served at. MEDIA_BASE_URL
+// can point it at a CDN in front of the storage.
+func MediaURL(key string) string {
+	baseURL := os.Getenv("MEDIA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "/media"
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + key
+}
+
+// posterFiles checks that data is a JPEG or PNG image and returns it along
+// with its thumbnails, keyed by the film and a hash of the image so a new
+// poster never reuses the address of a cached one
+func posterFiles(filmID uint64, data []byte) ([]posterFile, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := posterTypes[contentType]
+	if !ok {
+		return nil, errors.New("poster must be a JPEG or PNG image")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("poster image is invalid")
+	}
+	if config.Width*config.Height > maxPosterPixels {
+		return nil, errors.New("poster image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("poster image is invalid")
+	}
+
+	sum := sha256.Sum256(data)
+	base := "films/" + strconv.FormatUint(filmID, 10) + "/poster-" + hex.EncodeToString(sum[:6])
+
+	// Every thumbnail is scaled from the same opaque copy
+	flat := utils.FlattenImage(img)
+
+	files := []posterFile{{key: base + ext, contentType: contentType, data: data}}
+	for _, size := range posterSizes {
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, utils.ResizeImage(flat, size.width), &jpeg.Options{Quality: 85})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, posterFile{key: posterThumbnailKey(base+ext, size.name), contentType: "image/jpeg", data: buf.Bytes()})
+	}
+	return files, nil
+}
+
+func posterThumbnailKey(key string, size string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "-" + size + ".jpg"
+}
+
+// posterKeys returns the keys of a stored poster and its thumbnails
+func posterKeys(key string) []string {
+	if key == "" {
+		return nil
+	}
+
+	keys := []string{key}
+	for _, size := range posterSizes {
+		keys = append(keys, posterThumbnailKey(key, size.name))
+	}
+	return keys
+}
+
+// posterThumbnails returns the addresses of the thumbnails of a poster
+func posterThumbnails(key string) map[string]string {
+	thumbnails := map[string]string{}
+	for _, size := range posterSizes {
+		thumbnails[size.name] = MediaURL(posterThumbnailKey(key, size.name))
+	}
+	return thumbnails
+}
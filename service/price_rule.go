@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+
+	"github.com/jinzhu/copier"
+)
+
+type priceRuleService struct {
+	priceRuleRepository repository.PriceRuleRepository
+}
+
+type PriceRuleService interface {
+	CreateNewPriceRule(ctx context.Context, priceRuleDTO dto.PriceRuleCreateRequest) (entity.PriceRule, error)
+	GetAllPriceRules(ctx context.Context) ([]entity.PriceRule, error)
+	GetPriceRuleByID(ctx context.Context, id uint64) (entity.PriceRule, error)
+	UpdatePriceRule(ctx context.Context, priceRuleDTO dto.PriceRuleCreateRequest, priceRule entity.PriceRule) (entity.PriceRule, error)
+	DeletePriceRuleByID(ctx context.Context, id uint64) error
+}
+
+func NewPriceRuleService(priceRuleR repository.PriceRuleRepository) PriceRuleService {
+	return &priceRuleService{priceRuleRepository: priceRuleR}
+}
+
+func (priceRuleS *priceRuleService) CreateNewPriceRule(ctx context.Context, priceRuleDTO dto.PriceRuleCreateRequest) (entity.PriceRule, error) {
+	// Copy PriceRuleDTO to empty newly created price rule var
+	var priceRule entity.PriceRule
+	copier.Copy(&priceRule, &priceRuleDTO)
+
+	// create new price rule
+	newPriceRule, err := priceRuleS.priceRuleRepository.CreateNewPriceRule(ctx, nil, priceRule)
+	if err != nil {
+		return entity.PriceRule{}, err
+	}
+	return newPriceRule, nil
+}
+
+func (priceRuleS *priceRuleService) GetAllPriceRules(ctx context.Context) ([]entity.PriceRule, error) {
+	priceRules, err := priceRuleS.priceRuleRepository.GetAllPriceRules(ctx, nil)
+	if err != nil {
+		return []entity.PriceRule{}, err
+	}
+	return priceRules, nil
+}
+
+func (priceRuleS *priceRuleService) GetPriceRuleByID(ctx context.Context, id uint64) (entity.PriceRule, error) {
+	priceRule, err := priceRuleS.priceRuleRepository.GetPriceRuleByID(ctx, nil, id)
+	if err != nil {
+		return entity.PriceRule{}, err
+	}
+	return priceRule, nil
+}
+
+func (priceRuleS *priceRuleService) UpdatePriceRule(ctx context.Context, priceRuleDTO dto.PriceRuleCreateRequest, priceRule entity.PriceRule) (entity.PriceRule, error) {
+	priceRule, err := priceRuleS.priceRuleRepository.UpdatePriceRule(ctx, nil, priceRuleDTO, priceRule)
+	if err != nil {
+		return entity.PriceRule{}, err
+	}
+	return priceRule, nil
+}
+
+func (priceRuleS *priceRuleService) DeletePriceRuleByID(ctx context.Context, id uint64) error {
+	err := priceRuleS.priceRuleRepository.DeletePriceRuleByID(ctx, nil, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
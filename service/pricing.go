@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/entity"
+	"time"
+)
+
+// priceSpots prices every spot of a booking. Seats start at the session price,
+// or at the area base price for sessions without one, and every matching rule
+// adds its amount on top. Rules in another currency than the seat are skipped.
+func priceSpots(session entity.Session, area entity.Area, rules []entity.PriceRule, spots []entity.Spot) ([]entity.TransactionLine, common.Money) {
+	basePrice := sessionBasePrice(session, area)
+
+	// Day and time rules follow the clock of the cinema
+	startsAt := session.StartsAt.In(common.Location())
+
+	var lines []entity.TransactionLine
+	total := common.Money{Currency: basePrice.Currency}
+	for _, spot := range spots {
+		line := entity.TransactionLine{
+			SpotID:      spot.ID,
+			SpotName:    spot.Name(),
+			SpotType:    spot.Type,
+			BasePrice:   basePrice,
+			Adjustments: []entity.PriceAdjustment{},
+			Price:       basePrice,
+		}
+
+		for _, rule := range rules {
+			if !rule.Amount.SameCurrency(basePrice) || !priceRuleMatches(rule, area.ID, spot.Type, startsAt) {
+				continue
+			}
+			line.Adjustments = append(line.Adjustments, entity.PriceAdjustment{Rule: rule.Name, Amount: rule.Amount})
+			line.Price = line.Price.Add(rule.Amount)
+		}
+
+		if line.Price.IsNegative() {
+			line.Price.Amount = 0
+		}
+		total = total.Add(line.Price)
+		lines = append(lines, line)
+	}
+	return lines, total
+}
+
+// sessionBasePrice returns the price of a seat of the session before any rule
+func sessionBasePrice(session entity.Session, area entity.Area) common.Money {
+	if session.Price.IsPositive() {
+		return session.Price.WithDefaultCurrency()
+	}
+	return area.BasePrice.WithDefaultCurrency()
+}
+
+// priceRuleMatches reports whether rule applies to a spot of a session
+// starting at startsAt
+func priceRuleMatches(rule entity.PriceRule, areaID uint64, spotType string, startsAt time.Time) bool {
+	if rule.AreaID != nil && *rule.AreaID != areaID {
+		return false
+	}
+
+	if rule.SpotType != "" && rule.SpotType != spotType {
+		return false
+	}
+
+	if rule.DayType != "" {
+		weekend := startsAt.Weekday() == time.Saturday || startsAt.Weekday() == time.Sunday
+		if weekend != (rule.DayType == entity.DayWeekend) {
+			return false
+		}
+	}
+
+	if rule.StartTime != "" && rule.EndTime != "" {
+		start, err := time.Parse("15:04", rule.StartTime)
+		if err != nil {
+			return false
+		}
+		end, err := time.Parse("15:04", rule.EndTime)
+		if err != nil {
+			return false
+		}
+
+		minute := startsAt.Hour()*60 + startsAt.Minute()
+		startMinute := start.Hour()*60 + start.Minute()
+		endMinute := end.Hour()*60 + end.Minute()
+		if startMinute <= endMinute {
+			return minute >= startMinute && minute < endMinute
+		}
+		// The window wraps past midnight
+		return minute >= startMinute || minute < endMinute
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fp-rpl/common"
+	"fp-rpl/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPriceRuleMatches(t *testing.T) {
+	otherArea := uint64(2)
+	area := uint64(1)
+	saturday := time.Date(2026, time.October, 17, 19, 30, 0, 0, common.Location())
+	wednesday := time.Date(2026, time.October, 14, 19, 30, 0, 0, common.Location())
+
+	tests := []struct {
+		name     string
+		rule     entity.PriceRule
+		spotType string
+		startsAt time.Time
+		matches  bool
+	}{
+		{"empty rule", entity.PriceRule{}, entity.SpotRegular, wednesday, true},
+		{"same area", entity.PriceRule{AreaID: &area}, entity.SpotRegular, wednesday, true},
+		{"other area", entity.PriceRule{AreaID: &otherArea}, entity.SpotRegular, wednesday, false},
+		{"same spot type", entity.PriceRule{SpotType: entity.SpotVIP}, entity.SpotVIP, wednesday, true},
+		{"other spot type", entity.PriceRule{SpotType: entity.SpotVIP}, entity.SpotRegular, wednesday, false},
+		{"weekend on saturday", entity.PriceRule{DayType: entity.DayWeekend}, entity.SpotRegular, saturday, true},
+		{"weekend on wednesday", entity.PriceRule{DayType: entity.DayWeekend}, entity.SpotRegular, wednesday, false},
+		{"weekday on wednesday", entity.PriceRule{DayType: entity.DayWeekday}, entity.SpotRegular, wednesday, true},
+		{"weekday on saturday", entity.PriceRule{DayType: entity.DayWeekday}, entity.SpotRegular, saturday, false},
+		{"inside time window", entity.PriceRule{StartTime: "18:00", EndTime: "22:00"}, entity.SpotRegular, wednesday, true},
+		{"at end of time window", entity.PriceRule{StartTime: "17:00", EndTime: "19:30"}, entity.SpotRegular, wednesday, false},
+		{"outside time window", entity.PriceRule{StartTime: "10:00", EndTime: "17:00"}, entity.SpotRegular, wednesday, false},
+		{"late inside window past midnight", entity.PriceRule{StartTime: "22:00", EndTime: "02:00"}, entity.SpotRegular, wednesday.Add(4 * time.Hour), true},
+		{"early inside window past midnight", entity.PriceRule{StartTime: "22:00", EndTime: "02:00"}, entity.SpotRegular, wednesday.Add(6 * time.Hour), true},
+		{"outside window past midnight", entity.PriceRule{StartTime: "22:00", EndTime: "02:00"}, entity.SpotRegular, wednesday, false},
+		{"invalid time window", entity.PriceRule{StartTime: "7pm", EndTime: "22:00"}, entity.SpotRegular, wednesday, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := priceRuleMatches(test.rule, area, test.spotType, test.startsAt); got != test.matches {
+				t.Errorf("priceRuleMatches() = %v, want %v", got, test.matches)
+			}
+		})
+	}
+}
+
+func TestPriceSpots(t *testing.T) {
+	area := entity.Area{BasePrice: common.NewMoney(40000, common.DefaultCurrency)}
+	area.ID = 1
+	startsAt := time.Date(2026, time.October, 17, 19, 30, 0, 0, common.Location())
+
+	regular := entity.Spot{Row: "A", Number: 1, Type: entity.SpotRegular}
+	vip := entity.Spot{Row: "B", Number: 2, Type: entity.SpotVIP}
+
+	weekend := entity.PriceRule{Name: "weekend", DayType: entity.DayWeekend, Amount: common.NewMoney(10000, common.DefaultCurrency)}
+	vipRule := entity.PriceRule{Name: "vip", SpotType: entity.SpotVIP, Amount: common.NewMoney(25000.5, common.DefaultCurrency)}
+	foreign := entity.PriceRule{Name: "foreign", Amount: common.Money{Amount: 500, Currency: "USD"}}
+	discount := entity.PriceRule{Name: "discount", Amount: common.NewMoney(-100000, common.DefaultCurrency)}
+
+	tests := []struct {
+		name    string
+		session entity.Session
+		rules   []entity.PriceRule
+		spots   []entity.Spot
+		prices  []int64
+		total   int64
+	}{
+		{"area base price", entity.Session{StartsAt: startsAt}, nil, []entity.Spot{regular}, []int64{4000000}, 4000000},
+		{"session price", entity.Session{StartsAt: startsAt, Price: common.NewMoney(35000, "")}, nil, []entity.Spot{regular}, []int64{3500000}, 3500000},
+		{"matching rules add up", entity.Session{StartsAt: startsAt}, []entity.PriceRule{weekend, vipRule}, []entity.Spot{regular, vip}, []int64{5000000, 7500050}, 12500050},
+		{"rule in another currency", entity.Session{StartsAt: startsAt}, []entity.PriceRule{foreign}, []entity.Spot{regular}, []int64{4000000}, 4000000},
+		{"price never below zero", entity.Session{StartsAt: startsAt}, []entity.PriceRule{discount}, []entity.Spot{regular}, []int64{0}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, total := priceSpots(test.session, area, test.rules, test.spots)
+
+			var prices []int64
+			for _, line := range lines {
+				if line.Price.Currency != common.DefaultCurrency {
+					t.Errorf("line %s currency = %q, want %q", line.SpotName, line.Price.Currency, common.DefaultCurrency)
+				}
+				prices = append(prices, line.Price.Amount)
+			}
+			if !reflect.DeepEqual(prices, test.prices) {
+				t.Errorf("prices = %v, want %v", prices, test.prices)
+			}
+			if total.Amount != test.total || total.Currency != common.DefaultCurrency {
+				t.Errorf("total = %v, want %d %s", total, test.total, common.DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	tests := []struct {
+		name  string
+		money common.Money
+		want  int64
+	}{
+		{"major units", common.NewMoney(45000.5, common.DefaultCurrency), 4500050},
+		{"half a minor unit", common.NewMoney(0.125, common.DefaultCurrency), 13},
+		{"percent rounds half up", common.Money{Amount: 1005}.Percent(10), 101},
+		{"percent rounds down", common.Money{Amount: 1004}.Percent(10), 100},
+		{"percent of a negative amount", common.Money{Amount: -1005}.Percent(10), -101},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.money.Amount != test.want {
+				t.Errorf("Amount = %d, want %d", test.money.Amount, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"strings"
+
+	"github.com/jinzhu/copier"
+)
+
+type promoCodeService struct {
+	promoCodeRepository repository.PromoCodeRepository
+}
+
+type PromoCodeService interface {
+	CreateNewPromoCode(ctx context.Context, promoCodeDTO dto.PromoCodeCreateRequest) (entity.PromoCode, error)
+	GetAllPromoCodes(ctx context.Context) ([]entity.PromoCode, error)
+	GetPromoCodeByID(ctx context.Context, id uint64) (entity.PromoCode, error)
+	GetPromoCodeByCode(ctx context.Context, code string) (entity.PromoCode, error)
+	UpdatePromoCode(ctx context.Context, promoCodeDTO dto.PromoCodeCreateRequest, promoCode entity.PromoCode) (entity.PromoCode, error)
+	DeletePromoCodeByID(ctx context.Context, id uint64) error
+}
+
+func NewPromoCodeService(promoCodeR repository.PromoCodeRepository) PromoCodeService {
+	return &promoCodeService{promoCodeRepository: promoCodeR}
+}
+
+// NormalizePromoCode makes promo codes case and whitespace insensitive
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (promoCodeS *promoCodeService) CreateNewPromoCode(ctx context.Context, promoCodeDTO dto.PromoCodeCreateRequest) (entity.PromoCode, error) {
+	// Copy PromoCodeDTO to empty newly created promo code var
+	var promoCode entity.PromoCode
+	copier.Copy(&promoCode, &promoCodeDTO)
+	promoCode.Code = NormalizePromoCode(promoCode.Code)
+
+	// create new promo code
+	newPromoCode, err := promoCodeS.promoCodeRepository.CreateNewPromoCode(ctx, nil, promoCode)
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+	return newPromoCode, nil
+}
+
+func (promoCodeS *promoCodeService) GetAllPromoCodes(ctx context.Context) ([]entity.PromoCode, error) {
+	promoCodes, err := promoCodeS.promoCodeRepository.GetAllPromoCodes(ctx, nil)
+	if err != nil {
+		return []entity.PromoCode{}, err
+	}
+	return promoCodes, nil
+}
+
+func (promoCodeS *promoCodeService) GetPromoCodeByID(ctx context.Context, id uint64) (entity.PromoCode, error) {
+	promoCode, err := promoCodeS.promoCodeRepository.GetPromoCodeByID(ctx, nil, id)
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+	return promoCode, nil
+}
+
+func (promoCodeS *promoCodeService) GetPromoCodeByCode(ctx context.Context, code string) (entity.PromoCode, error) {
+	promoCode, err := promoCodeS.promoCodeRepository.GetPromoCodeByCode(ctx, nil, NormalizePromoCode(code))
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+	return promoCode, nil
+}
+
+func (promoCodeS *promoCodeService) UpdatePromoCode(ctx context.Context, promoCodeDTO dto.PromoCodeCreateRequest, promoCode entity.PromoCode) (entity.PromoCode, error) {
+	promoCodeDTO.Code = NormalizePromoCode(promoCodeDTO.Code)
+	promoCode, err := promoCodeS.promoCodeRepository.UpdatePromoCode(ctx, nil, promoCodeDTO, promoCode)
+	if err != nil {
+		return entity.PromoCode{}, err
+	}
+	return promoCode, nil
+}
+
+func (promoCodeS *promoCodeService) DeletePromoCodeByID(ctx context.Context, id uint64) error {
+	err := promoCodeS.promoCodeRepository.DeletePromoCodeByID(ctx, nil, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"fp-rpl/repository"
+	"reflect"
+	"strings"
+)
+
+// ErrRolePermissionDenied is returned when staff edit a role to hand out
+// permissions they don't hold themselves
+var ErrRolePermissionDenied = errors.New("you cannot grant permissions you don't hold")
+
+type roleService struct {
+	roleRepository repository.RoleRepository
+	userRepository repository.UserRepository
+}
+
+type RoleService interface {
+	CreateNewRole(ctx context.Context, roleDTO dto.RoleCreateRequest, editor AccessClaims) (entity.Role, error)
+	GetAllRoles(ctx context.Context) ([]entity.Role, error)
+	GetRoleByID(ctx context.Context, id uint64) (entity.Role, error)
+	GetRoleByName(ctx context.Context, name string) (entity.Role, error)
+	GetAllPermissions(ctx context.Context) ([]entity.Permission, error)
+	UpdateRole(ctx context.Context, roleDTO dto.RoleUpdateRequest, role entity.Role, editor AccessClaims) (entity.Role, error)
+	DeleteRole(ctx context.Context, role entity.Role) error
+	AssignUserRole(ctx context.Context, roleDTO dto.UserRoleUpdateRequest, username string, assigner AccessClaims) (entity.User, error)
+}
+
+func NewRoleService(roleR repository.RoleRepository, userR repository.UserRepository) RoleService {
+	return &roleService{roleRepository: roleR, userRepository: userR}
+}
+
+// checkRolePermissions fails unless a role exists and editor holds every
+// permission of it, so staff can't hand out or act on more than they have
+func checkRolePermissions(ctx context.Context, roleR repository.RoleRepository, name string, editor AccessClaims) error {
+	role, err := roleR.GetRoleByName(ctx, nil, name)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(role, entity.Role{}) {
+		return errors.New("role " + name + " not found")
+	}
+
+	for _, permission := range role.PermissionNames() {
+		if !editor.HasPermission(permission) {
+			return ErrUserPermissionDenied
+		}
+	}
+	return nil
+}
+
+// checkRoleGrant fails unless editor may give users the role
+func checkRoleGrant(ctx context.Context, roleR repository.RoleRepository, name string, editor AccessClaims) error {
+	if !editor.HasPermission(entity.PermissionRolesManage) {
+		return ErrUserPermissionDenied
+	}
+	return checkRolePermissions(ctx, roleR, name, editor)
+}
+
+// checkPermissionsHeld fails unless editor holds every one of permissions
+func checkPermissionsHeld(permissions []string, editor AccessClaims) error {
+	for _, permission := range permissions {
+		if !editor.HasPermission(permission) {
+			return ErrRolePermissionDenied
+		}
+	}
+	return nil
+}
+
+// rolePermissions loads the permissions a role request names, failing when
+// one of them doesn't exist
+func (roleS *roleService) rolePermissions(ctx context.Context, names []string) ([]entity.Permission, error) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !entity.IsPermission(name) {
+			return nil, errors.New("permission " + name + " not found")
+		}
+		if seen[name] {
+			return nil, errors.New("permission " + name + " is listed twice")
+		}
+		seen[name] = true
+	}
+
+	permissions, err := roleS.roleRepository.GetPermissionsByNames(ctx, nil, names)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) != len(names) {
+		return nil, errors.New("permissions have not been set up yet")
+	}
+	return permissions, nil
+}
+
+// CreateNewRole creates a role out of permissions the editor holds
+func (roleS *roleService) CreateNewRole(ctx context.Context, roleDTO dto.RoleCreateRequest, editor AccessClaims) (entity.Role, error) {
+	err := checkPermissionsHeld(roleDTO.Permissions, editor)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	permissions, err := roleS.rolePermissions(ctx, roleDTO.Permissions)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	role := entity.Role{
+		Name:        strings.TrimSpace(roleDTO.Name),
+		Description: strings.TrimSpace(roleDTO.Description),
+		Permissions: permissions,
+	}
+	newRole, err := roleS.roleRepository.CreateNewRole(ctx, nil, role)
+	if err != nil {
+		return entity.Role{}, err
+	}
+	return newRole, nil
+}
+
+func (roleS *roleService) GetAllRoles(ctx context.Context) ([]entity.Role, error) {
+	roles, err := roleS.roleRepository.GetAllRoles(ctx, nil)
+	if err != nil {
+		return []entity.Role{}, err
+	}
+	return roles, nil
+}
+
+func (roleS *roleService) GetRoleByID(ctx context.Context, id uint64) (entity.Role, error) {
+	role, err := roleS.roleRepository.GetRoleByID(ctx, nil, id)
+	if err != nil {
+		return entity.Role{}, err
+	}
+	return role, nil
+}
+
+func (roleS *roleService) GetRoleByName(ctx context.Context, name string) (entity.Role, error) {
+	role, err := roleS.roleRepository.GetRoleByName(ctx, nil, name)
+	if err != nil {
+		return entity.Role{}, err
+	}
+	return role, nil
+}
+
+func (roleS *roleService) GetAllPermissions(ctx context.Context) ([]entity.Permission, error) {
+	permissions, err := roleS.roleRepository.GetAllPermissions(ctx, nil)
+	if err != nil {
+		return []entity.Permission{}, err
+	}
+	return permissions, nil
+}
+
+// UpdateRole changes the description and permissions of a role. Users with
+// the role get the new permissions when their access token is next renewed.
+// Only editors holding every permission of the role, before and after, can
+// change it.
+func (roleS *roleService) UpdateRole(ctx context.Context, roleDTO dto.RoleUpdateRequest, role entity.Role, editor AccessClaims) (entity.Role, error) {
+	if roleDTO.Permissions != nil && entity.IsBuiltInRole(role.Name) {
+		return entity.Role{}, errors.New("permissions of the " + role.Name + " role cannot be changed")
+	}
+
+	err := checkPermissionsHeld(role.PermissionNames(), editor)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	err = checkPermissionsHeld(roleDTO.Permissions, editor)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	permissions, err := roleS.rolePermissions(ctx, roleDTO.Permissions)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	role.Description = strings.TrimSpace(roleDTO.Description)
+
+	tx, err := roleS.roleRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	role, err = roleS.roleRepository.UpdateRole(ctx, tx, role)
+	if err != nil {
+		roleS.roleRepository.RollbackTx(ctx, tx)
+		return entity.Role{}, err
+	}
+
+	if roleDTO.Permissions != nil {
+		err = roleS.roleRepository.ReplaceRolePermissions(ctx, tx, role, permissions)
+		if err != nil {
+			roleS.roleRepository.RollbackTx(ctx, tx)
+			return entity.Role{}, err
+		}
+		role.Permissions = permissions
+	}
+
+	err = roleS.roleRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Role{}, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a role no user has
+func (roleS *roleService) DeleteRole(ctx context.Context, role entity.Role) error {
+	if entity.IsBuiltInRole(role.Name) {
+		return errors.New("the " + role.Name + " role cannot be deleted")
+	}
+
+	count, err := roleS.roleRepository.CountUsersWithRole(ctx, nil, role.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("role is still assigned to users")
+	}
+
+	return roleS.roleRepository.DeleteRoleByID(ctx, nil, role.ID)
+}
+
+// AssignUserRole gives a user another role. Nobody can change their own role,
+// so the last admin cannot lock everyone out by accident. The assigner must
+// hold every permission of both the current and the new role of the user.
+func (roleS *roleService) AssignUserRole(ctx context.Context, roleDTO dto.UserRoleUpdateRequest, username string, assigner AccessClaims) (entity.User, error) {
+	user, err := roleS.userRepository.GetUserByIdentifier(ctx, nil, username, username)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		return entity.User{}, nil
+	}
+
+	if user.ID == assigner.UserID {
+		return entity.User{}, errors.New("you cannot change your own role")
+	}
+
+	err = checkRolePermissions(ctx, roleS.roleRepository, user.Role, assigner)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	err = checkRoleGrant(ctx, roleS.roleRepository, roleDTO.Role, assigner)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user, err = roleS.userRepository.UpdateRoleUser(ctx, nil, roleDTO.Role, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxScheduleSlots caps how many sessions a single schedule request creates
+const maxScheduleSlots = 500
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// scheduleSlots expands a schedule request into the start of every session it
+// asks for, in chronological order
+func scheduleSlots(scheduleDTO dto.SessionScheduleRequest, location *time.Location) ([]time.Time, error) {
+	startDate, err := time.ParseInLocation("2006-01-02", scheduleDTO.StartDate, location)
+	if err != nil {
+		return nil, errors.New("start date must use the YYYY-MM-DD format")
+	}
+
+	endDate, err := time.ParseInLocation("2006-01-02", scheduleDTO.EndDate, location)
+	if err != nil {
+		return nil, errors.New("end date must use the YYYY-MM-DD format")
+	}
+
+	if endDate.Before(startDate) {
+		return nil, errors.New("end date must not be before start date")
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, day := range scheduleDTO.Days {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+		if !ok {
+			return nil, errors.New("day " + day + " is invalid")
+		}
+		days[weekday] = true
+	}
+
+	var showtimes []time.Time
+	for _, showtime := range scheduleDTO.Showtimes {
+		clock, err := time.Parse("15:04", showtime)
+		if err != nil {
+			return nil, errors.New("showtime " + showtime + " must use the HH:MM format")
+		}
+		showtimes = append(showtimes, clock)
+	}
+
+	if len(showtimes) == 0 {
+		return nil, errors.New("at least one showtime is required")
+	}
+
+	var slots []time.Time
+	seen := map[time.Time]bool{}
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		if len(days) > 0 && !days[date.Weekday()] {
+			continue
+		}
+
+		for _, clock := range showtimes {
+			slot := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+			if seen[slot] {
+				continue
+			}
+			seen[slot] = true
+			slots = append(slots, slot)
+
+			if len(slots) > maxScheduleSlots {
+				return nil, errors.New("schedule must not create more than " + strconv.Itoa(maxScheduleSlots) + " sessions")
+			}
+		}
+	}
+
+	if len(slots) == 0 {
+		return nil, errors.New("no day between start date and end date matches the schedule")
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Before(slots[j])
+	})
+	return slots, nil
+}
+
+// sessionEnd returns when the area is free again after a session of film
+// starting at startsAt
+func sessionEnd(startsAt time.Time, film entity.Film, area entity.Area) time.Time {
+	return startsAt.Add(time.Duration(film.Duration+area.TurnaroundMinutes) * time.Minute)
+}
+
+// sessionConflicts returns the sessions whose screening window, turnaround
+// included, overlaps one of film starting at startsAt in area
+func sessionConflicts(sessions []entity.Session, film entity.Film, area entity.Area, startsAt time.Time) []dto.SessionConflict {
+	endsAt := sessionEnd(startsAt, film, area)
+	turnaround := time.Duration(area.TurnaroundMinutes) * time.Minute
+
+	conflicts := []dto.SessionConflict{}
+	for _, session := range sessions {
+		end := session.EndsAt.Add(turnaround)
+		if session.StartsAt.Before(endsAt) && startsAt.Before(end) {
+			conflicts = append(conflicts, dto.SessionConflict{
+				SessionID: session.ID,
+				FilmID:    session.FilmID,
+				StartsAt:  session.StartsAt,
+				EndsAt:    end,
+			})
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleSlots(t *testing.T) {
+	location := time.FixedZone("WIB", 7*60*60)
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, location)
+	}
+
+	tests := []struct {
+		name     string
+		schedule dto.SessionScheduleRequest
+		slots    []time.Time
+	}{
+		{
+			"every day",
+			dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-13", Showtimes: []string{"19:00", "13:30"}},
+			[]time.Time{at(12, 13, 30), at(12, 19, 0), at(13, 13, 30), at(13, 19, 0)},
+		},
+		{
+			"single day",
+			dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-12", Showtimes: []string{"10:00"}},
+			[]time.Time{at(12, 10, 0)},
+		},
+		{
+			"matching days only",
+			dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-18", Days: []string{"Saturday", " sunday "}, Showtimes: []string{"10:00"}},
+			[]time.Time{at(17, 10, 0), at(18, 10, 0)},
+		},
+		{
+			"repeated showtime",
+			dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-12", Showtimes: []string{"10:00", "10:00"}},
+			[]time.Time{at(12, 10, 0)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slots, err := scheduleSlots(test.schedule, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(slots, test.slots) {
+				t.Errorf("scheduleSlots() = %v, want %v", slots, test.slots)
+			}
+		})
+	}
+}
+
+func TestScheduleSlotsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule dto.SessionScheduleRequest
+	}{
+		{"invalid start date", dto.SessionScheduleRequest{StartDate: "12-10-2026", EndDate: "2026-10-12", Showtimes: []string{"10:00"}}},
+		{"invalid end date", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "tomorrow", Showtimes: []string{"10:00"}}},
+		{"end before start", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-11", Showtimes: []string{"10:00"}}},
+		{"invalid day", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-12", Days: []string{"funday"}, Showtimes: []string{"10:00"}}},
+		{"invalid showtime", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-12", Showtimes: []string{"7pm"}}},
+		{"no showtime", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-12"}},
+		{"no matching day", dto.SessionScheduleRequest{StartDate: "2026-10-12", EndDate: "2026-10-13", Days: []string{"sunday"}, Showtimes: []string{"10:00"}}},
+		{"too many sessions", dto.SessionScheduleRequest{StartDate: "2026-01-01", EndDate: "2026-12-31", Showtimes: []string{"10:00", "14:00"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slots, err := scheduleSlots(test.schedule, time.UTC)
+			if err == nil {
+				t.Errorf("scheduleSlots() = %v, want an error", slots)
+			}
+		})
+	}
+}
+
+func TestSessionConflicts(t *testing.T) {
+	film := entity.Film{Duration: 120}
+	area := entity.Area{TurnaroundMinutes: 30}
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2026, time.October, 12, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Taken from 13:00 until 15:00, and until 15:30 with its turnaround
+	existing := entity.Session{FilmID: 7, StartsAt: at(13, 0), EndsAt: at(15, 0)}
+	existing.ID = 1
+
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		conflict bool
+	}{
+		{"same start", at(13, 0), true},
+		{"starts during the session", at(14, 0), true},
+		{"starts during the turnaround", at(15, 15), true},
+		{"starts right after the turnaround", at(15, 30), false},
+		{"ends during the session", at(11, 0), true},
+		{"turnaround ends during the session", at(10, 45), true},
+		{"turnaround ends right at the start", at(10, 30), false},
+		{"long before", at(8, 0), false},
+		{"long after", at(20, 0), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conflicts := sessionConflicts([]entity.Session{existing}, film, area, test.startsAt)
+			if (len(conflicts) > 0) != test.conflict {
+				t.Fatalf("sessionConflicts() = %v, want conflict %v", conflicts, test.conflict)
+			}
+
+			if test.conflict {
+				want := dto.SessionConflict{SessionID: 1, FilmID: 7, StartsAt: at(13, 0), EndsAt: at(15, 30)}
+				if conflicts[0] != want {
+					t.Errorf("conflict = %+v, want %+v", conflicts[0], want)
+				}
+			}
+		})
+	}
+}
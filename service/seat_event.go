@@ -0,0 +1,118 @@
+package service
+
+import (
+	"fp-rpl/dto"
+	"fp-rpl/entity"
+	"sync"
+	"time"
+)
+
+// seatEventBuffer is how many events a subscriber may fall behind before it
+// is dropped
+const seatEventBuffer = 32
+
+// seatEventService is an in-process event bus for seat status changes. It
+// only reaches subscribers connected to the same node.
+type seatEventService struct {
+	mu          sync.Mutex
+	subscribers map[uint64]map[chan dto.SeatEvent]struct{}
+}
+
+type SeatEventService interface {
+	Subscribe(sessionID uint64) (<-chan dto.SeatEvent, func())
+	Publish(spots []entity.Spot)
+}
+
+func NewSeatEventService() SeatEventService {
+	return &seatEventService{
+		subscribers: map[uint64]map[chan dto.SeatEvent]struct{}{},
+	}
+}
+
+// Subscribe returns the seat events of a session and a function ending the
+// subscription. The channel is closed when the subscriber falls too far
+// behind, after which it should reload the seat map and subscribe again.
+func (seatEventS *seatEventService) Subscribe(sessionID uint64) (<-chan dto.SeatEvent, func()) {
+	events := make(chan dto.SeatEvent, seatEventBuffer)
+
+	seatEventS.mu.Lock()
+	if seatEventS.subscribers[sessionID] == nil {
+		seatEventS.subscribers[sessionID] = map[chan dto.SeatEvent]struct{}{}
+	}
+	seatEventS.subscribers[sessionID][events] = struct{}{}
+	seatEventS.mu.Unlock()
+
+	unsubscribe := func() {
+		seatEventS.mu.Lock()
+		defer seatEventS.mu.Unlock()
+		seatEventS.remove(sessionID, events)
+	}
+	return events, unsubscribe
+}
+
+// Publish sends the current status of spots to the subscribers of their
+// sessions. It must only be called once the change is committed.
+func (seatEventS *seatEventService) Publish(spots []entity.Spot) {
+	if len(spots) == 0 {
+		return
+	}
+
+	now := time.Now()
+	events := map[uint64]*dto.SeatEvent{}
+	var order []uint64
+	for _, spot := range spots {
+		event, ok := events[spot.SessionID]
+		if !ok {
+			event = &dto.SeatEvent{SessionID: spot.SessionID}
+			events[spot.SessionID] = event
+			order = append(order, spot.SessionID)
+		}
+		event.Seats = append(event.Seats, seatMapSeat(spot, now))
+	}
+
+	seatEventS.mu.Lock()
+	defer seatEventS.mu.Unlock()
+	for _, sessionID := range order {
+		for subscriber := range seatEventS.subscribers[sessionID] {
+			select {
+			case subscriber <- *events[sessionID]:
+			default:
+				seatEventS.remove(sessionID, subscriber)
+			}
+		}
+	}
+}
+
+// remove drops a subscriber, the caller must hold mu
+func (seatEventS *seatEventService) remove(sessionID uint64, events chan dto.SeatEvent) {
+	if _, ok := seatEventS.subscribers[sessionID][events]; !ok {
+		return
+	}
+
+	delete(seatEventS.subscribers[sessionID], events)
+	if len(seatEventS.subscribers[sessionID]) == 0 {
+		delete(seatEventS.subscribers, sessionID)
+	}
+	close(events)
+}
+
+// seatMapSeat describes a spot as seen on the seat map, without exposing who
+// booked or held it
+func seatMapSeat(spot entity.Spot, now time.Time) dto.SeatMapSeat {
+	status := dto.SeatFree
+	if spot.IsBlocked {
+		status = dto.SeatBlocked
+	} else if spot.TransactionID != nil {
+		status = dto.SeatSold
+	} else if spot.IsHeld(now) {
+		status = dto.SeatHeld
+	}
+
+	return dto.SeatMapSeat{
+		Name:   spot.Name(),
+		Row:    spot.Row,
+		Number: spot.Number,
+		Type:   spot.Type,
+		Status: status,
+	}
+}
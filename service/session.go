@@ -2,91 +2,232 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
-	"fp-rpl/utils"
+	"reflect"
+	"time"
 
 	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
 )
 
 type sessionService struct {
 	sessionRepository repository.SessionRepository
 	spotRepository    repository.SpotRepository
+	areaRepository    repository.AreaRepository
 }
 
 type SessionService interface {
-	GetSessionByTimeAndPlace(ctx context.Context, sessionDTO dto.SessionCreateRequest) (entity.Session, error)
 	GetSessionByID(ctx context.Context, id uint64) (entity.Session, error)
-	CreateNewSession(ctx context.Context, sessionDTO dto.SessionCreateRequest, spotCount int, spotPerRow int) (entity.Session, error)
-	GetAllSessions(ctx context.Context) ([]entity.Session, error)
+	CreateNewSession(ctx context.Context, sessionDTO dto.SessionCreateRequest, film entity.Film, area entity.Area) (entity.Session, error)
+	ScheduleSessions(ctx context.Context, scheduleDTO dto.SessionScheduleRequest, film entity.Film, area entity.Area) (dto.SessionScheduleResponse, error)
+	CheckSessionConflicts(ctx context.Context, film entity.Film, area entity.Area, startsAt time.Time) (dto.SessionConflictResponse, error)
+	GetShowtimes(ctx context.Context, date time.Time, filmSlug string, areaID uint64) (dto.ShowtimesResponse, error)
+	GetAllSessions(ctx context.Context, query common.Query) ([]entity.Session, common.Meta, error)
 	DeleteSessionByID(ctx context.Context, id uint64) error
 	GetSessionDetailByID(ctx context.Context, id uint64) (entity.Session, error)
+	GetSessionSeatMap(ctx context.Context, session entity.Session, area entity.Area) (dto.SeatMapResponse, error)
 }
 
-func NewSessionService(sessionR repository.SessionRepository, spotR repository.SpotRepository) SessionService {
+func NewSessionService(sessionR repository.SessionRepository, spotR repository.SpotRepository, areaR repository.AreaRepository) SessionService {
 	return &sessionService{
 		sessionRepository: sessionR,
 		spotRepository:    spotR,
+		areaRepository:    areaR,
 	}
 }
 
-func (sessionS *sessionService) GetSessionByTimeAndPlace(ctx context.Context, sessionDTO dto.SessionCreateRequest) (entity.Session, error) {
-	session, err := sessionS.sessionRepository.GetSessionByTimeAndAreaID(ctx, nil, sessionDTO.Time, sessionDTO.AreaID)
+func (sessionS *sessionService) GetSessionByID(ctx context.Context, id uint64) (entity.Session, error) {
+	session, err := sessionS.sessionRepository.GetSessionByID(ctx, nil, id)
 	if err != nil {
 		return entity.Session{}, err
 	}
 	return session, nil
 }
 
-func (sessionS *sessionService) GetSessionByID(ctx context.Context, id uint64) (entity.Session, error) {
-	session, err := sessionS.sessionRepository.GetSessionByID(ctx, nil, id)
+// CreateNewSession creates a session unless it overlaps another session of
+// the area, counting the film duration and the area turnaround
+func (sessionS *sessionService) CreateNewSession(ctx context.Context, sessionDTO dto.SessionCreateRequest, film entity.Film, area entity.Area) (entity.Session, error) {
+	startsAt, err := time.Parse(time.RFC3339, sessionDTO.Time)
 	if err != nil {
-		return entity.Session{}, err
+		return entity.Session{}, errors.New("failed to process session time")
 	}
-	return session, nil
-}
 
-func (sessionS *sessionService) CreateNewSession(ctx context.Context, sessionDTO dto.SessionCreateRequest, spotCount int, spotPerRow int) (entity.Session, error) {
 	var session entity.Session
 	copier.Copy(&session, &sessionDTO)
+	session.StartsAt = startsAt
+	session.EndsAt = startsAt.Add(time.Duration(film.Duration) * time.Minute)
 
-	newSession, err := sessionS.sessionRepository.CreateNewSession(ctx, nil, session)
+	tx, err := sessionS.sessionRepository.BeginTx(ctx)
 	if err != nil {
+		return entity.Session{}, errors.New("failed to process session create request")
+	}
+
+	area, sessions, err := sessionS.lockAreaSessions(ctx, tx, area.ID, session.StartsAt, session.EndsAt)
+	if err != nil {
+		sessionS.sessionRepository.RollbackTx(ctx, tx)
 		return entity.Session{}, err
 	}
 
-	i, j := 1, 1
-	rowCount := spotCount / spotPerRow
-
-	// Create Spots according to spot_count and spot_per_row
-	for i <= rowCount {
-		j = 1
-		for j <= spotPerRow {
-			spot := entity.Spot{
-				Row:       string(utils.IntToChar(i)),
-				Number:    j,
-				SessionID: newSession.ID,
-			}
-
-			_, err := sessionS.spotRepository.CreateNewSpot(ctx, nil, spot)
-			if err != nil {
-				return entity.Session{}, err
-			}
-			j++
+	if len(sessionConflicts(sessions, film, area, startsAt)) > 0 {
+		sessionS.sessionRepository.RollbackTx(ctx, tx)
+		return entity.Session{}, errors.New("session overlaps another session in the area")
+	}
+
+	newSession, err := sessionS.createSession(ctx, tx, session, area)
+	if err != nil {
+		sessionS.sessionRepository.RollbackTx(ctx, tx)
+		return entity.Session{}, errors.New("failed to process session create request")
+	}
+
+	err = sessionS.sessionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Session{}, errors.New("failed to process session create request")
+	}
+	return newSession, nil
+}
+
+// CheckSessionConflicts lists the sessions a session of film starting at
+// startsAt in area would overlap
+func (sessionS *sessionService) CheckSessionConflicts(ctx context.Context, film entity.Film, area entity.Area, startsAt time.Time) (dto.SessionConflictResponse, error) {
+	endsAt := sessionEnd(startsAt, film, area)
+	turnaround := time.Duration(area.TurnaroundMinutes) * time.Minute
+	sessions, err := sessionS.sessionRepository.GetSessionsByAreaIDBetween(ctx, nil, area.ID, startsAt.Add(-turnaround), endsAt)
+	if err != nil {
+		return dto.SessionConflictResponse{}, err
+	}
+
+	conflictCheck := dto.SessionConflictResponse{
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Conflicts: sessionConflicts(sessions, film, area, startsAt),
+	}
+	return conflictCheck, nil
+}
+
+// lockAreaSessions locks the area, so sessions are added to it one request
+// at a time, and returns it with the sessions that may clash with sessions
+// running from from to to
+func (sessionS *sessionService) lockAreaSessions(ctx context.Context, tx *gorm.DB, areaID uint64, from time.Time, to time.Time) (entity.Area, []entity.Session, error) {
+	area, err := sessionS.areaRepository.LockAreaByID(ctx, tx, areaID)
+	if err != nil {
+		return entity.Area{}, nil, errors.New("failed to get session area")
+	}
+
+	if reflect.DeepEqual(area, entity.Area{}) {
+		return entity.Area{}, nil, errors.New("area not found")
+	}
+
+	turnaround := time.Duration(area.TurnaroundMinutes) * time.Minute
+	sessions, err := sessionS.sessionRepository.GetSessionsByAreaIDBetween(ctx, tx, areaID, from.Add(-turnaround), to.Add(turnaround))
+	if err != nil {
+		return entity.Area{}, nil, errors.New("failed to get area sessions")
+	}
+	return area, sessions, nil
+}
+
+// ScheduleSessions creates every session of a schedule in a single db
+// transaction. Slots that have passed or clash with an existing session are
+// skipped and reported instead of failing the whole schedule.
+func (sessionS *sessionService) ScheduleSessions(ctx context.Context, scheduleDTO dto.SessionScheduleRequest, film entity.Film, area entity.Area) (dto.SessionScheduleResponse, error) {
+	slots, err := scheduleSlots(scheduleDTO, common.Location())
+	if err != nil {
+		return dto.SessionScheduleResponse{}, err
+	}
+
+	tx, err := sessionS.sessionRepository.BeginTx(ctx)
+	if err != nil {
+		return dto.SessionScheduleResponse{}, errors.New("failed to process session schedule request")
+	}
+
+	lastEnd := slots[len(slots)-1].Add(time.Duration(film.Duration) * time.Minute)
+	area, sessions, err := sessionS.lockAreaSessions(ctx, tx, area.ID, slots[0], lastEnd)
+	if err != nil {
+		sessionS.sessionRepository.RollbackTx(ctx, tx)
+		return dto.SessionScheduleResponse{}, err
+	}
+
+	schedule := dto.SessionScheduleResponse{
+		Created: []dto.SessionScheduleSlot{},
+		Skipped: []dto.SessionScheduleSlot{},
+	}
+	now := time.Now()
+	for _, slot := range slots {
+		slotTime := slot.Format(time.RFC3339)
+		if slot.Before(now) {
+			schedule.Skipped = append(schedule.Skipped, dto.SessionScheduleSlot{Time: slotTime, Reason: "session time has passed"})
+			continue
+		}
+
+		if !film.IsPlayingAt(slot) {
+			schedule.Skipped = append(schedule.Skipped, dto.SessionScheduleSlot{Time: slotTime, Reason: "film is not playing on this day"})
+			continue
+		}
+
+		conflicts := sessionConflicts(sessions, film, area, slot)
+		if len(conflicts) > 0 {
+			schedule.Skipped = append(schedule.Skipped, dto.SessionScheduleSlot{Time: slotTime, SessionID: conflicts[0].SessionID, Reason: "overlaps another session in the area"})
+			continue
 		}
-		i++
+
+		session := entity.Session{
+			StartsAt: slot,
+			EndsAt:   slot.Add(time.Duration(film.Duration) * time.Minute),
+			Price:    scheduleDTO.Price,
+			FilmID:   scheduleDTO.FilmID,
+			AreaID:   area.ID,
+		}
+		newSession, err := sessionS.createSession(ctx, tx, session, area)
+		if err != nil {
+			sessionS.sessionRepository.RollbackTx(ctx, tx)
+			return dto.SessionScheduleResponse{}, errors.New("failed to create session at " + slotTime)
+		}
+		schedule.Created = append(schedule.Created, dto.SessionScheduleSlot{Time: slotTime, SessionID: newSession.ID})
+
+		// Later slots of the schedule must not overlap the new session either
+		sessions = append(sessions, newSession)
 	}
 
+	err = sessionS.sessionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return dto.SessionScheduleResponse{}, errors.New("failed to process session schedule request")
+	}
+	return schedule, nil
+}
+
+// createSession creates a session together with its spots, laid out
+// according to the area
+func (sessionS *sessionService) createSession(ctx context.Context, tx *gorm.DB, session entity.Session, area entity.Area) (entity.Session, error) {
+	spots, err := buildAreaSpots(area)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	newSession, err := sessionS.sessionRepository.CreateNewSession(ctx, tx, session)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	for i := range spots {
+		spots[i].SessionID = newSession.ID
+	}
+
+	_, err = sessionS.spotRepository.CreateNewSpots(ctx, tx, spots)
+	if err != nil {
+		return entity.Session{}, err
+	}
 	return newSession, nil
 }
 
-func (sessionS *sessionService) GetAllSessions(ctx context.Context) ([]entity.Session, error) {
-	sessions, err := sessionS.sessionRepository.GetAllSessions(ctx, nil)
+func (sessionS *sessionService) GetAllSessions(ctx context.Context, query common.Query) ([]entity.Session, common.Meta, error) {
+	sessions, total, err := sessionS.sessionRepository.GetAllSessions(ctx, nil, query)
 	if err != nil {
-		return []entity.Session{}, err
+		return []entity.Session{}, common.Meta{}, err
 	}
-	return sessions, nil
+	return sessions, query.Meta(total), nil
 }
 
 func (sessionS *sessionService) DeleteSessionByID(ctx context.Context, id uint64) error {
@@ -110,3 +251,129 @@ func (sessionS *sessionService) GetSessionDetailByID(ctx context.Context, id uin
 	}
 	return session, nil
 }
+
+// GetSessionSeatMap lays the session spots out as a grid of rows and columns,
+// exposing only the status of each seat and not who booked it
+func (sessionS *sessionService) GetSessionSeatMap(ctx context.Context, session entity.Session, area entity.Area) (dto.SeatMapResponse, error) {
+	spots, err := sessionS.spotRepository.GetSpotsBySessionID(ctx, nil, session.ID)
+	if err != nil {
+		return dto.SeatMapResponse{}, err
+	}
+
+	// Spots made before layouts existed have no grid position, their row
+	// letter and number give it
+	for i := range spots {
+		if spots[i].GridRow == 0 {
+			spots[i].GridRow = int(spots[i].Row[0]-'A') + 1
+			spots[i].GridColumn = spots[i].Number
+		}
+	}
+
+	rowCount, columnCount := 0, area.SpotPerRow
+	for _, spot := range spots {
+		if spot.GridRow > rowCount {
+			rowCount = spot.GridRow
+		}
+		if spot.GridColumn > columnCount {
+			columnCount = spot.GridColumn
+		}
+	}
+
+	seats := make([][]*dto.SeatMapSeat, rowCount)
+	for i := range seats {
+		seats[i] = make([]*dto.SeatMapSeat, columnCount)
+	}
+
+	now := time.Now()
+	for _, spot := range spots {
+		seat := seatMapSeat(spot, now)
+		seats[spot.GridRow-1][spot.GridColumn-1] = &seat
+	}
+
+	seatMap := dto.SeatMapResponse{
+		SessionID: session.ID,
+		Rows:      rowCount,
+		Columns:   columnCount,
+		Seats:     seats,
+	}
+	return seatMap, nil
+}
+
+// GetShowtimes lists the sessions of a day that haven't started yet, grouped
+// by film and then by area in order of their first session
+func (sessionS *sessionService) GetShowtimes(ctx context.Context, date time.Time, filmSlug string, areaID uint64) (dto.ShowtimesResponse, error) {
+	now := time.Now()
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, common.Location())
+	from, to := day, day.AddDate(0, 0, 1)
+	if from.Before(now) {
+		from = now
+	}
+
+	showtimes := dto.ShowtimesResponse{
+		Date:  day.Format("2006-01-02"),
+		Films: []dto.ShowtimeFilm{},
+	}
+	if !from.Before(to) {
+		return showtimes, nil
+	}
+
+	sessions, err := sessionS.sessionRepository.GetShowtimes(ctx, nil, from, to, filmSlug, areaID)
+	if err != nil {
+		return dto.ShowtimesResponse{}, err
+	}
+
+	sessionIDs := make([]uint64, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	available, err := sessionS.spotRepository.CountAvailableSpotsBySessionIDs(ctx, nil, sessionIDs, now)
+	if err != nil {
+		return dto.ShowtimesResponse{}, err
+	}
+
+	filmIndex := map[uint64]int{}
+	areaIndex := map[uint64]map[uint64]int{}
+	for _, session := range sessions {
+		if session.Film == nil || session.Area == nil {
+			continue
+		}
+
+		fi, ok := filmIndex[session.FilmID]
+		if !ok {
+			fi = len(showtimes.Films)
+			filmIndex[session.FilmID] = fi
+			areaIndex[session.FilmID] = map[uint64]int{}
+			showtimes.Films = append(showtimes.Films, dto.ShowtimeFilm{
+				ID:        session.Film.ID,
+				Title:     session.Film.Title,
+				Slug:      session.Film.Slug,
+				Duration:  session.Film.Duration,
+				AgeRating: session.Film.AgeRating,
+				Image:     session.Film.Image,
+				Areas:     []dto.ShowtimeArea{},
+			})
+		}
+		film := &showtimes.Films[fi]
+
+		ai, ok := areaIndex[session.FilmID][session.AreaID]
+		if !ok {
+			ai = len(film.Areas)
+			areaIndex[session.FilmID][session.AreaID] = ai
+			film.Areas = append(film.Areas, dto.ShowtimeArea{
+				ID:       session.Area.ID,
+				Name:     session.Area.Name,
+				Sessions: []dto.ShowtimeSession{},
+			})
+		}
+
+		film.Areas[ai].Sessions = append(film.Areas[ai].Sessions, dto.ShowtimeSession{
+			ID:             session.ID,
+			StartsAt:       session.StartsAt,
+			EndsAt:         session.EndsAt,
+			Price:          sessionBasePrice(session, *session.Area),
+			RemainingSeats: available[session.ID],
+		})
+	}
+	return showtimes, nil
+}
@@ -2,21 +2,58 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
+	"fp-rpl/utils"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type spotService struct {
-	spotRepository repository.SpotRepository
+	spotRepository   repository.SpotRepository
+	seatEventService SeatEventService
+	holdDuration     time.Duration
+	holdLimit        int
 }
 
 type SpotService interface {
 	GetSpotBySessionIDAndAttributes(ctx context.Context, sessionID uint64, row string, number int) (entity.Spot, error)
 	UpdateSpot(ctx context.Context, spot entity.Spot) (entity.Spot, error)
+	HoldSpots(ctx context.Context, sessionID uint64, userID uint64, spotsName []string) ([]entity.Spot, error)
+	ReleaseSpotHolds(ctx context.Context, sessionID uint64, userID uint64, spotsName []string) error
+	ReleaseExpiredHolds(ctx context.Context) (int64, error)
 }
 
-func NewSpotService(spotR repository.SpotRepository) SpotService {
-	return &spotService{spotRepository: spotR}
+func NewSpotService(spotR repository.SpotRepository, seatEventS SeatEventService) SpotService {
+	return &spotService{
+		spotRepository:   spotR,
+		seatEventService: seatEventS,
+		holdDuration:     getSpotHoldDuration(),
+		holdLimit:        getSpotHoldLimit(),
+	}
+}
+
+func getSpotHoldDuration() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SPOT_HOLD_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// getSpotHoldLimit is how many spots of a session one user can hold at once
+func getSpotHoldLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("SPOT_HOLD_LIMIT"))
+	if err != nil || limit <= 0 {
+		limit = 8
+	}
+	return limit
 }
 
 func (spotS *spotService) GetSpotBySessionIDAndAttributes(ctx context.Context, sessionID uint64, row string, number int) (entity.Spot, error) {
@@ -32,5 +69,176 @@ func (spotS *spotService) UpdateSpot(ctx context.Context, spot entity.Spot) (ent
 	if err != nil {
 		return entity.Spot{}, err
 	}
+
+	spotS.seatEventService.Publish([]entity.Spot{spot})
 	return spot, nil
-}
\ No newline at end of file
+}
+
+func (spotS *spotService) HoldSpots(ctx context.Context, sessionID uint64, userID uint64, spotsName []string) ([]entity.Spot, error) {
+	tx, err := spotS.spotRepository.BeginTx(ctx)
+	if err != nil {
+		return nil, errors.New("failed to process spot hold request")
+	}
+
+	err = spotS.spotRepository.LockUserHolds(ctx, tx, sessionID, userID)
+	if err != nil {
+		spotS.spotRepository.RollbackTx(ctx, tx)
+		return nil, errors.New("failed to process spot hold request")
+	}
+
+	spots, err := lockSpots(ctx, spotS.spotRepository, tx, sessionID, spotsName)
+	if err != nil {
+		spotS.spotRepository.RollbackTx(ctx, tx)
+		return nil, err
+	}
+
+	now := time.Now()
+	held, err := spotS.spotRepository.CountActiveHoldsOfUser(ctx, tx, sessionID, userID, now)
+	if err != nil {
+		spotS.spotRepository.RollbackTx(ctx, tx)
+		return nil, errors.New("failed to process spot hold request")
+	}
+
+	for _, spot := range spots {
+		if !spot.IsHeldBy(userID, now) {
+			held++
+		}
+	}
+	if held > int64(spotS.holdLimit) {
+		spotS.spotRepository.RollbackTx(ctx, tx)
+		return nil, errors.New("you can hold at most " + strconv.Itoa(spotS.holdLimit) + " spots of a session")
+	}
+
+	heldUntil := now.Add(spotS.holdDuration)
+	for i := range spots {
+		spotName := spots[i].Name()
+		if spots[i].IsBlocked {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return nil, errors.New("spot with name " + spotName + " is not available")
+		}
+
+		if spots[i].TransactionID != nil {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return nil, errors.New("spot with name " + spotName + " is reserved")
+		}
+
+		if spots[i].IsHeld(now) && !spots[i].IsHeldBy(userID, now) {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return nil, errors.New("spot with name " + spotName + " is held by another user")
+		}
+
+		// Holding a spot again must not push its hold further
+		if spots[i].IsHeldBy(userID, now) {
+			continue
+		}
+
+		spots[i].HeldByUserID = &userID
+		spots[i].HeldUntil = &heldUntil
+		spots[i], err = spotS.spotRepository.UpdateSpot(ctx, tx, spots[i])
+		if err != nil {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return nil, errors.New("failed to hold spot " + spotName)
+		}
+	}
+
+	err = spotS.spotRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return nil, errors.New("failed to process spot hold request")
+	}
+
+	spotS.seatEventService.Publish(spots)
+	return spots, nil
+}
+
+func (spotS *spotService) ReleaseSpotHolds(ctx context.Context, sessionID uint64, userID uint64, spotsName []string) error {
+	tx, err := spotS.spotRepository.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to process spot release request")
+	}
+
+	spots, err := lockSpots(ctx, spotS.spotRepository, tx, sessionID, spotsName)
+	if err != nil {
+		spotS.spotRepository.RollbackTx(ctx, tx)
+		return err
+	}
+
+	now := time.Now()
+	for i := range spots {
+		spotName := spots[i].Name()
+		if !spots[i].IsHeldBy(userID, now) {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return errors.New("spot with name " + spotName + " is not held by you")
+		}
+
+		spots[i].ClearHold()
+		spots[i], err = spotS.spotRepository.UpdateSpot(ctx, tx, spots[i])
+		if err != nil {
+			spotS.spotRepository.RollbackTx(ctx, tx)
+			return errors.New("failed to release spot " + spotName)
+		}
+	}
+
+	err = spotS.spotRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return errors.New("failed to process spot release request")
+	}
+
+	spotS.seatEventService.Publish(spots)
+	return nil
+}
+
+func (spotS *spotService) ReleaseExpiredHolds(ctx context.Context) (int64, error) {
+	released, err := spotS.spotRepository.ReleaseExpiredHolds(ctx, nil, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	spotS.seatEventService.Publish(released)
+	return int64(len(released)), nil
+}
+
+// lockSpots takes row locks on the named spots of a session inside tx. Spots
+// are locked in a fixed order so concurrent requests can't deadlock. Names
+// are compared once parsed, so A1 and A01 count as the same spot.
+func lockSpots(ctx context.Context, spotR repository.SpotRepository, tx *gorm.DB, sessionID uint64, spotsName []string) ([]entity.Spot, error) {
+	type spotKey struct {
+		row    string
+		number int
+	}
+
+	keys := make([]spotKey, 0, len(spotsName))
+	for _, spotName := range spotsName {
+		spotRow, spotNumber, err := utils.ParseSpotName(spotName)
+		if err != nil {
+			return nil, errors.New("failed to process spot name")
+		}
+		keys = append(keys, spotKey{row: spotRow, number: spotNumber})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].row != keys[j].row {
+			return keys[i].row < keys[j].row
+		}
+		return keys[i].number < keys[j].number
+	})
+
+	var spots []entity.Spot
+	for i, key := range keys {
+		spotName := key.row + strconv.Itoa(key.number)
+		if i > 0 && keys[i-1] == key {
+			return nil, errors.New("spot with name " + spotName + " is requested more than once")
+		}
+
+		spot, err := spotR.LockSpotBySessionIDAndAttributes(ctx, tx, sessionID, key.row, key.number)
+		if err != nil {
+			return nil, errors.New("failed to process spot")
+		}
+
+		if reflect.DeepEqual(spot, entity.Spot{}) {
+			return nil, errors.New("spot with name " + spotName + " not found")
+		}
+
+		spots = append(spots, spot)
+	}
+	return spots, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrMediaNotFound = errors.New("media not found")
+
+// Storage keeps uploaded media files under slash separated keys such as
+// films/1/poster-0a1b2c3d4e5f.jpg. Put reads the file from body as it goes,
+// so large files such as trailers are never held in memory whole.
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, body io.ReadSeeker) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage returns the storage chosen by STORAGE_DRIVER, the local
+// filesystem unless it is set to s3. The S3 storage needs its bucket,
+// endpoint and credentials, the server does not start without them.
+func NewStorage() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "s3":
+		config := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+			return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY must be set with STORAGE_DRIVER s3")
+		}
+		return NewS3Storage(config), nil
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStorage(dir), nil
+	default:
+		return nil, errors.New("STORAGE_DRIVER " + driver + " is not supported")
+	}
+}
+
+// MediaContentType returns the content type a stored file is served with,
+// going by its extension. The types of uploads are known up front as the
+// system may not have them registered.
+func MediaContentType(key string) string {
+	ext := path.Ext(key)
+	for _, types := range []map[string]string{posterTypes, trailerTypes} {
+		for contentType, typeExt := range types {
+			if typeExt == ext {
+				return contentType
+			}
+		}
+	}
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return contentType
+}
+
+// validMediaKey rejects keys that could escape the storage root
+func validMediaKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// localStorage keeps media files in a directory of the server
+type localStorage struct {
+	dir string
+}
+
+func NewLocalStorage(dir string) Storage {
+	return &localStorage{dir: dir}
+}
+
+func (s *localStorage) path(key string) (string, error) {
+	if !validMediaKey(key) {
+		return "", errors.New("media key " + key + " is invalid")
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, contentType string, body io.ReadSeeker) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Written next to its final path and renamed so a file is never served
+	// half written
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
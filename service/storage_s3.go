@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points the S3 storage at a bucket of AWS S3 or of a compatible
+// server such as MinIO. Endpoint is the base URL of the server, for example
+// https://s3.ap-southeast-1.amazonaws.com
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// s3Storage keeps media files in an S3 bucket, addressed path style and
+// signed with AWS Signature Version 4
+type s3Storage struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3Storage(config S3Config) Storage {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	// Long enough for a trailer to be uploaded
+	return &s3Storage{config: config, client: &http.Client{Timeout: 10 * time.Minute}}
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, contentType string, body io.ReadSeeker) error {
+	res, err := s.do(ctx, http.MethodPut, key, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New("s3 put of " + key + " failed with status " + strconv.Itoa(res.StatusCode))
+	}
+	return nil
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validMediaKey(key) {
+		return nil, ErrMediaNotFound
+	}
+
+	res, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrMediaNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.New("s3 get of " + key + " failed with status " + strconv.Itoa(res.StatusCode))
+	}
+	return res.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return errors.New("s3 delete of " + key + " failed with status " + strconv.Itoa(res.StatusCode))
+	}
+	return nil
+}
+
+// do sends a signed request for the object under key. The body is read
+// once to be hashed for the signature and then again to be sent.
+func (s *s3Storage) do(ctx context.Context, method string, key string, contentType string, body io.ReadSeeker) (*http.Response, error) {
+	if !validMediaKey(key) {
+		return nil, errors.New("media key " + key + " is invalid")
+	}
+
+	segments := strings.Split(key, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	path := "/" + url.PathEscape(s.config.Bucket) + "/" + strings.Join(segments, "/")
+
+	payloadHash := sha256Hex(nil)
+	var size int64
+	if body != nil {
+		hash := sha256.New()
+		_, err := body.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		size, err = io.Copy(hash, body)
+		if err != nil {
+			return nil, err
+		}
+		_, err = body.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		payloadHash = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = io.NopCloser(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.config.Endpoint+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, path, payloadHash, time.Now())
+
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 headers to req, whose body hashes
+// to payloadHash
+func (s *s3Storage) sign(req *http.Request, path string, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.config.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery sorts and encodes query parameters the way Signature
+// Version 4 expects them
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode escapes everything but unreserved characters, spaces included
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
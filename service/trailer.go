@@ -0,0 +1,68 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// MaxTrailerSize is the largest trailer file that can be uploaded, in bytes
+const MaxTrailerSize = 200 << 20
+
+// trailerTypes maps the accepted trailer content types to their extension
+var trailerTypes = map[string]string{
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// trailerFile checks that file is an MP4 or WebM video of at most
+// MaxTrailerSize and returns the key and content type to store it under. The
+// key holds a hash of the video so a new trailer never reuses the address of
+// a cached one. file is left at its start.
+func trailerFile(filmID uint64, file io.ReadSeeker) (string, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := trailerTypes[contentType]
+	if !ok {
+		return "", "", errors.New("trailer must be an MP4 or WebM video")
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(file, MaxTrailerSize+1))
+	if err != nil {
+		return "", "", err
+	}
+	if size > MaxTrailerSize {
+		return "", "", errors.New("trailer must be at most 200 MB")
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := hash.Sum(nil)
+	key := "films/" + strconv.FormatUint(filmID, 10) + "/trailer-" + hex.EncodeToString(sum[:6]) + ext
+	return key, contentType, nil
+}
+
+// trailerKeys returns the keys of a stored trailer
+func trailerKeys(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return []string{key}
+}
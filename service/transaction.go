@@ -2,27 +2,85 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
 )
 
+// ErrPaymentProcessing is returned when the payment gateway could not tell
+// whether a charge went through. The charge is retried by SettlePayments.
+var ErrPaymentProcessing = errors.New("payment is still being processed")
+
 type transactionService struct {
 	transactionRepository repository.TransactionRepository
+	spotRepository        repository.SpotRepository
+	sessionRepository     repository.SessionRepository
+	priceRuleRepository   repository.PriceRuleRepository
+	promoCodeRepository   repository.PromoCodeRepository
+	userRepository        repository.UserRepository
+	paymentGateway        PaymentGateway
+	seatEventService      SeatEventService
+	paymentTimeout        time.Duration
+	cancellationCutoff    time.Duration
 }
 
 type TransactionService interface {
 	CreateNewTransaction(ctx context.Context, transactionDTO dto.TransactionMakeRequest) (entity.Transaction, error)
-	GetAllTransactions(ctx context.Context) ([]entity.Transaction, error)
+	MakeTransaction(ctx context.Context, transactionDTO dto.TransactionMakeRequest) (entity.Transaction, error)
+	GetAllTransactions(ctx context.Context, query common.Query) ([]entity.Transaction, common.Meta, error)
 	GetTransactionByID(ctx context.Context, id uint64) (entity.Transaction, error)
 	GetTransactionsByUserID(ctx context.Context, userID uint64) ([]entity.Transaction, error)
 	DeleteTransactionByID(ctx context.Context, id uint64) error
+	GetTransactionByCode(ctx context.Context, code string) (entity.Transaction, error)
+	PayTransaction(ctx context.Context, id uint64) (entity.Transaction, error)
+	ConfirmTransaction(ctx context.Context, id uint64, paymentReference string) (entity.Transaction, error)
+	CancelTransaction(ctx context.Context, id uint64) (entity.Transaction, error)
+	CancelMyTransaction(ctx context.Context, id uint64, reason string) (entity.Transaction, error)
+	RefundTransaction(ctx context.Context, id uint64) (entity.Transaction, error)
+	ExpirePendingTransactions(ctx context.Context) (int, error)
+	SettlePayments(ctx context.Context) (int, error)
 }
 
-func NewTransactionService(transactionR repository.TransactionRepository) TransactionService {
-	return &transactionService{transactionRepository: transactionR}
+func NewTransactionService(transactionR repository.TransactionRepository, spotR repository.SpotRepository, sessionR repository.SessionRepository, priceRuleR repository.PriceRuleRepository, promoCodeR repository.PromoCodeRepository, userR repository.UserRepository, paymentG PaymentGateway, seatEventS SeatEventService) TransactionService {
+	return &transactionService{
+		transactionRepository: transactionR,
+		spotRepository:        spotR,
+		sessionRepository:     sessionR,
+		priceRuleRepository:   priceRuleR,
+		promoCodeRepository:   promoCodeR,
+		userRepository:        userR,
+		paymentGateway:        paymentG,
+		seatEventService:      seatEventS,
+		paymentTimeout:        getPaymentTimeout(),
+		cancellationCutoff:    getCancellationCutoff(),
+	}
+}
+
+func getPaymentTimeout() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("PAYMENT_TIMEOUT_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func getCancellationCutoff() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("CANCELLATION_CUTOFF_MINUTES"))
+	if err != nil || minutes < 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
 }
 
 func (transactionS *transactionService) CreateNewTransaction(ctx context.Context, transactionDTO dto.TransactionMakeRequest) (entity.Transaction, error) {
@@ -38,12 +96,184 @@ func (transactionS *transactionService) CreateNewTransaction(ctx context.Context
 	return newTransaction, nil
 }
 
-func (transactionS *transactionService) GetAllTransactions(ctx context.Context) ([]entity.Transaction, error) {
-	transactions, err := transactionS.transactionRepository.GetAllTransactions(ctx, nil)
+// MakeTransaction books every requested spot and creates the transaction in a
+// single db transaction, so a spot can never be sold twice and a failure
+// halfway leaves nothing behind
+func (transactionS *transactionService) MakeTransaction(ctx context.Context, transactionDTO dto.TransactionMakeRequest) (entity.Transaction, error) {
+	tx, err := transactionS.transactionRepository.BeginTx(ctx)
 	if err != nil {
-		return []entity.Transaction{}, err
+		return entity.Transaction{}, errors.New("failed to process transaction make request")
 	}
-	return transactions, nil
+
+	newTransaction, spots, err := transactionS.makeTransaction(ctx, tx, transactionDTO)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, err
+	}
+
+	err = transactionS.transactionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Transaction{}, errors.New("failed to process transaction make request")
+	}
+
+	transactionS.seatEventService.Publish(spots)
+	return newTransaction, nil
+}
+
+func (transactionS *transactionService) makeTransaction(ctx context.Context, tx *gorm.DB, transactionDTO dto.TransactionMakeRequest) (entity.Transaction, []entity.Spot, error) {
+	spots, err := lockSpots(ctx, transactionS.spotRepository, tx, transactionDTO.SessionID, transactionDTO.SpotsName)
+	if err != nil {
+		return entity.Transaction{}, nil, err
+	}
+
+	now := time.Now()
+	for _, spot := range spots {
+		spotName := spot.Name()
+		if spot.IsBlocked {
+			return entity.Transaction{}, nil, errors.New("spot with name " + spotName + " is not available")
+		}
+
+		if spot.TransactionID != nil {
+			return entity.Transaction{}, nil, errors.New("spot with name " + spotName + " is reserved")
+		}
+
+		// A held spot can only be converted into a transaction by its holder
+		if spot.IsHeld(now) && !spot.IsHeldBy(transactionDTO.UserID, now) {
+			return entity.Transaction{}, nil, errors.New("spot with name " + spotName + " is held by another user")
+		}
+	}
+
+	session, err := transactionS.sessionRepository.GetSessionForBookingByID(ctx, tx, transactionDTO.SessionID)
+	if err != nil || session.Area == nil {
+		return entity.Transaction{}, nil, errors.New("failed to get session of transaction")
+	}
+
+	if session.Film != nil && session.Film.MinimumAge() > 0 {
+		err = transactionS.checkAge(ctx, tx, transactionDTO.UserID, session)
+		if err != nil {
+			return entity.Transaction{}, nil, err
+		}
+	}
+
+	priceRules, err := transactionS.priceRuleRepository.GetAllPriceRules(ctx, tx)
+	if err != nil {
+		return entity.Transaction{}, nil, errors.New("failed to get price rules")
+	}
+
+	var transaction entity.Transaction
+	copier.Copy(&transaction, &transactionDTO)
+	transaction.Lines, transaction.SubtotalPrice = priceSpots(session, *session.Area, priceRules, spots)
+	transaction.TotalPrice = transaction.SubtotalPrice
+
+	if transactionDTO.PromoCode != "" {
+		promoCode, err := transactionS.redeemPromoCode(ctx, tx, transactionDTO, session, len(spots), now)
+		if err != nil {
+			return entity.Transaction{}, nil, err
+		}
+
+		transaction.PromoCodeID = &promoCode.ID
+		transaction.DiscountPrice = promoCode.Discount(transaction.SubtotalPrice)
+		transaction.TotalPrice = transaction.SubtotalPrice.Sub(transaction.DiscountPrice)
+	}
+
+	// Spots stay reserved for the transaction until it is paid or expires
+	expiresAt := now.Add(transactionS.paymentTimeout)
+	transaction.Status = entity.TransactionPending
+	transaction.ExpiresAt = &expiresAt
+
+	newTransaction, err := transactionS.transactionRepository.CreateNewTransaction(ctx, tx, transaction)
+	if err != nil {
+		return entity.Transaction{}, nil, errors.New("failed to process transaction make request")
+	}
+
+	for i := range spots {
+		spots[i].TransactionID = &newTransaction.ID
+		spots[i].ClearHold()
+
+		spots[i], err = transactionS.spotRepository.UpdateSpot(ctx, tx, spots[i])
+		if err != nil {
+			return entity.Transaction{}, nil, errors.New("failed to reserve spot " + spots[i].Name())
+		}
+	}
+
+	return newTransaction, spots, nil
+}
+
+// checkAge refuses bookings of rated films by account holders who have not
+// reached the minimum age of the film on the day of the session
+func (transactionS *transactionService) checkAge(ctx context.Context, tx *gorm.DB, userID uint64, session entity.Session) error {
+	user, err := transactionS.userRepository.GetUserByID(ctx, tx, userID)
+	if err != nil || reflect.DeepEqual(user, entity.User{}) {
+		return errors.New("failed to get user of transaction")
+	}
+
+	if user.BirthDate == nil {
+		return errors.New("birth date is required to book a film rated " + session.Film.AgeRating)
+	}
+
+	if user.AgeAt(session.StartsAt) < session.Film.MinimumAge() {
+		return errors.New("account holder must be at least " + strconv.Itoa(session.Film.MinimumAge()) + " years old to book this film")
+	}
+	return nil
+}
+
+// redeemPromoCode checks the promo code against the booking and counts one
+// use of it. The promo code row stays locked until the booking commits, so
+// concurrent bookings cannot go over its usage limits.
+func (transactionS *transactionService) redeemPromoCode(ctx context.Context, tx *gorm.DB, transactionDTO dto.TransactionMakeRequest, session entity.Session, spotCount int, now time.Time) (entity.PromoCode, error) {
+	promoCode, err := transactionS.promoCodeRepository.LockPromoCodeByCode(ctx, tx, NormalizePromoCode(transactionDTO.PromoCode))
+	if err != nil {
+		return entity.PromoCode{}, errors.New("failed to get promo code")
+	}
+
+	if reflect.DeepEqual(promoCode, entity.PromoCode{}) {
+		return entity.PromoCode{}, errors.New("promo code not found")
+	}
+
+	if (promoCode.ValidFrom != nil && now.Before(*promoCode.ValidFrom)) || (promoCode.ValidUntil != nil && !now.Before(*promoCode.ValidUntil)) {
+		return entity.PromoCode{}, errors.New("promo code is not valid at this time")
+	}
+
+	if spotCount < promoCode.MinSpots {
+		return entity.PromoCode{}, errors.New("promo code requires at least " + strconv.Itoa(promoCode.MinSpots) + " spots")
+	}
+
+	if promoCode.FilmID != nil && *promoCode.FilmID != session.FilmID {
+		return entity.PromoCode{}, errors.New("promo code is not valid for this film")
+	}
+
+	if promoCode.AreaID != nil && *promoCode.AreaID != session.AreaID {
+		return entity.PromoCode{}, errors.New("promo code is not valid for this area")
+	}
+
+	if promoCode.MaxUses > 0 && promoCode.UsedCount >= promoCode.MaxUses {
+		return entity.PromoCode{}, errors.New("promo code has been used up")
+	}
+
+	if promoCode.MaxUsesPerUser > 0 {
+		uses, err := transactionS.transactionRepository.CountActiveTransactionsByPromoCodeAndUserID(ctx, tx, promoCode.ID, transactionDTO.UserID)
+		if err != nil {
+			return entity.PromoCode{}, errors.New("failed to get promo code usage")
+		}
+
+		if uses >= int64(promoCode.MaxUsesPerUser) {
+			return entity.PromoCode{}, errors.New("promo code usage limit reached for this user")
+		}
+	}
+
+	err = transactionS.promoCodeRepository.AddPromoCodeUses(ctx, tx, promoCode.ID, 1)
+	if err != nil {
+		return entity.PromoCode{}, errors.New("failed to redeem promo code")
+	}
+	return promoCode, nil
+}
+
+func (transactionS *transactionService) GetAllTransactions(ctx context.Context, query common.Query) ([]entity.Transaction, common.Meta, error) {
+	transactions, total, err := transactionS.transactionRepository.GetAllTransactions(ctx, nil, query)
+	if err != nil {
+		return []entity.Transaction{}, common.Meta{}, err
+	}
+	return transactions, query.Meta(total), nil
 }
 
 func (transactionS *transactionService) GetTransactionByID(ctx context.Context, id uint64) (entity.Transaction, error) {
@@ -62,10 +292,325 @@ func (transactionS *transactionService) GetTransactionsByUserID(ctx context.Cont
 	return transactions, nil
 }
 
+// DeleteTransactionByID deletes a transaction once it is settled. Open
+// transactions still hold spots, promo code uses or money, so they must be
+// cancelled first.
 func (transactionS *transactionService) DeleteTransactionByID(ctx context.Context, id uint64) error {
-	err := transactionS.transactionRepository.DeleteTransactionByID(ctx, nil, id)
+	tx, err := transactionS.transactionRepository.BeginTx(ctx)
+	if err != nil {
+		return errors.New("failed to process transaction delete request")
+	}
+
+	transaction, err := transactionS.transactionRepository.LockTransactionByID(ctx, tx, id)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return errors.New("failed to process transaction delete request")
+	}
+
+	if !transaction.IsFinal() {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return errors.New("transaction with status " + transaction.Status + " must be cancelled before it is deleted")
+	}
+
+	if transaction.RefundOwed() {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return errors.New("transaction cannot be deleted before it is refunded")
+	}
+
+	err = transactionS.transactionRepository.DeleteTransactionByID(ctx, tx, id)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return errors.New("failed to delete transaction")
+	}
+
+	err = transactionS.transactionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return errors.New("failed to process transaction delete request")
+	}
+	return nil
+}
+
+func (transactionS *transactionService) GetTransactionByCode(ctx context.Context, code string) (entity.Transaction, error) {
+	transaction, err := transactionS.transactionRepository.GetTransactionByCode(ctx, nil, code)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+// PayTransaction charges a pending transaction without holding its lock
+// while the payment gateway is called. The transaction is marked
+// payment_pending with an idempotency key first, then charged, then settled.
+func (transactionS *transactionService) PayTransaction(ctx context.Context, id uint64) (entity.Transaction, error) {
+	transaction, err := transactionS.changeStatus(ctx, id, entity.TransactionPaymentPending, func(transaction *entity.Transaction) error {
+		if transaction.ExpiresAt != nil && !transaction.ExpiresAt.After(time.Now()) {
+			return errors.New("transaction has expired")
+		}
+
+		if transaction.PaymentKey == "" {
+			transaction.PaymentKey = uuid.NewString()
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return transactionS.settlePayment(ctx, transaction)
+}
+
+// settlePayment charges a payment_pending transaction and records the
+// outcome. A declined charge puts the transaction back to pending so it can
+// be paid again, any other failure leaves it payment_pending to be retried.
+func (transactionS *transactionService) settlePayment(ctx context.Context, transaction entity.Transaction) (entity.Transaction, error) {
+	reference, err := transactionS.paymentGateway.Charge(ctx, transaction)
+	if errors.Is(err, ErrPaymentDeclined) {
+		_, err = transactionS.changeStatus(ctx, transaction.ID, entity.TransactionPending, nil)
+		if err != nil {
+			return entity.Transaction{}, err
+		}
+		return entity.Transaction{}, errors.New("payment failed")
+	}
+	if err != nil {
+		return entity.Transaction{}, ErrPaymentProcessing
+	}
+
+	return transactionS.changeStatus(ctx, transaction.ID, entity.TransactionPaid, func(transaction *entity.Transaction) error {
+		transaction.PaymentReference = reference
+		return nil
+	})
+}
+
+func (transactionS *transactionService) ConfirmTransaction(ctx context.Context, id uint64, paymentReference string) (entity.Transaction, error) {
+	return transactionS.changeStatus(ctx, id, entity.TransactionPaid, func(transaction *entity.Transaction) error {
+		transaction.PaymentReference = paymentReference
+		return nil
+	})
+}
+
+func (transactionS *transactionService) CancelTransaction(ctx context.Context, id uint64) (entity.Transaction, error) {
+	transaction, err := transactionS.changeStatus(ctx, id, entity.TransactionCancelled, requestRefundIfPaid)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return transactionS.refundIfOwed(ctx, transaction), nil
+}
+
+// CancelMyTransaction cancels a booking on behalf of its owner, which is only
+// allowed until the cancellation cutoff before the session starts
+func (transactionS *transactionService) CancelMyTransaction(ctx context.Context, id uint64, reason string) (entity.Transaction, error) {
+	transaction, err := transactionS.changeStatus(ctx, id, entity.TransactionCancelled, func(transaction *entity.Transaction) error {
+		session, err := transactionS.sessionRepository.GetSessionByID(ctx, nil, transaction.SessionID)
+		if err != nil {
+			return errors.New("failed to get transaction session")
+		}
+
+		if time.Now().Add(transactionS.cancellationCutoff).After(session.StartsAt) {
+			return errors.New("booking can only be cancelled until " + transactionS.cancellationCutoff.String() + " before the session starts")
+		}
+
+		transaction.CancellationReason = reason
+		return requestRefundIfPaid(transaction)
+	})
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return transactionS.refundIfOwed(ctx, transaction), nil
+}
+
+func (transactionS *transactionService) RefundTransaction(ctx context.Context, id uint64) (entity.Transaction, error) {
+	transaction, err := transactionS.changeStatus(ctx, id, entity.TransactionRefunded, requestRefundIfPaid)
 	if err != nil {
-		return err
+		return entity.Transaction{}, err
+	}
+	return transactionS.refundIfOwed(ctx, transaction), nil
+}
+
+// requestRefundIfPaid records that the money of a paid transaction is owed
+// back, the refund itself is made once the status change is committed
+func requestRefundIfPaid(transaction *entity.Transaction) error {
+	if transaction.Status != entity.TransactionPaid {
+		return nil
 	}
+
+	if transaction.PaymentKey == "" {
+		transaction.PaymentKey = uuid.NewString()
+	}
+	now := time.Now()
+	transaction.RefundRequestedAt = &now
 	return nil
 }
+
+// refundIfOwed refunds a transaction that was paid before being cancelled or
+// refunded. The status change stands when the refund fails, SettlePayments
+// retries it.
+func (transactionS *transactionService) refundIfOwed(ctx context.Context, transaction entity.Transaction) entity.Transaction {
+	if !transaction.RefundOwed() {
+		return transaction
+	}
+
+	refunded, err := transactionS.settleRefund(ctx, transaction)
+	if err != nil {
+		log.Println("failed to refund transaction " + transaction.Code + ": " + err.Error())
+		return transaction
+	}
+	return refunded
+}
+
+// settleRefund gives the money of a transaction back through the payment
+// gateway and records when it did
+func (transactionS *transactionService) settleRefund(ctx context.Context, transaction entity.Transaction) (entity.Transaction, error) {
+	err := transactionS.paymentGateway.Refund(ctx, transaction)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+
+	tx, err := transactionS.transactionRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+
+	transaction, err = transactionS.transactionRepository.LockTransactionByID(ctx, tx, transaction.ID)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, err
+	}
+
+	// The refund may have been settled by another attempt in the meantime
+	if !transaction.RefundOwed() {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return transaction, nil
+	}
+
+	now := time.Now()
+	transaction.RefundedAt = &now
+	transaction, err = transactionS.transactionRepository.UpdateTransaction(ctx, tx, transaction)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, err
+	}
+
+	err = transactionS.transactionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+// SettlePayments retries the charges left payment_pending and the refunds
+// that failed, with the idempotency key of their first attempt. Recently
+// changed transactions are left to the request still settling them.
+func (transactionS *transactionService) SettlePayments(ctx context.Context) (int, error) {
+	transactions, err := transactionS.transactionRepository.GetUnsettledTransactions(ctx, nil, time.Now().Add(-time.Minute))
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, transaction := range transactions {
+		if transaction.Status == entity.TransactionPaymentPending {
+			_, err = transactionS.settlePayment(ctx, transaction)
+		} else {
+			_, err = transactionS.settleRefund(ctx, transaction)
+		}
+		if err != nil {
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// ExpirePendingTransactions expires every pending transaction past its
+// payment deadline and gives its spots back to the session
+func (transactionS *transactionService) ExpirePendingTransactions(ctx context.Context) (int, error) {
+	transactions, err := transactionS.transactionRepository.GetExpiredPendingTransactions(ctx, nil, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, transaction := range transactions {
+		_, err = transactionS.changeStatus(ctx, transaction.ID, entity.TransactionExpired, nil)
+		if err != nil {
+			// The transaction may have been paid or cancelled in the meantime
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// changeStatus moves a transaction to status inside a db transaction. apply
+// runs on the locked transaction before it is saved and aborts the change on
+// error.
+func (transactionS *transactionService) changeStatus(ctx context.Context, id uint64, status string, apply func(transaction *entity.Transaction) error) (entity.Transaction, error) {
+	tx, err := transactionS.transactionRepository.BeginTx(ctx)
+	if err != nil {
+		return entity.Transaction{}, errors.New("failed to process transaction status change")
+	}
+
+	transaction, err := transactionS.transactionRepository.LockTransactionByID(ctx, tx, id)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, errors.New("failed to process transaction status change")
+	}
+
+	if reflect.DeepEqual(transaction, entity.Transaction{}) {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, errors.New("transaction not found")
+	}
+
+	if !transaction.CanTransitionTo(status) {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, errors.New("transaction with status " + transaction.Status + " cannot be " + status)
+	}
+
+	if apply != nil {
+		err = apply(&transaction)
+		if err != nil {
+			transactionS.transactionRepository.RollbackTx(ctx, tx)
+			return entity.Transaction{}, err
+		}
+	}
+
+	now := time.Now()
+	switch status {
+	case entity.TransactionPaid:
+		transaction.PaidAt = &now
+	case entity.TransactionCancelled:
+		transaction.CancelledAt = &now
+	}
+	transaction.Status = status
+
+	var released []entity.Spot
+	if entity.ReleasesSpots(status) {
+		released, err = transactionS.spotRepository.ReleaseSpotsByTransactionID(ctx, tx, transaction.ID)
+		if err != nil {
+			transactionS.transactionRepository.RollbackTx(ctx, tx)
+			return entity.Transaction{}, errors.New("failed to release transaction spots")
+		}
+
+		// A released booking gives its promo code use back
+		if transaction.PromoCodeID != nil {
+			err = transactionS.promoCodeRepository.AddPromoCodeUses(ctx, tx, *transaction.PromoCodeID, -1)
+			if err != nil {
+				transactionS.transactionRepository.RollbackTx(ctx, tx)
+				return entity.Transaction{}, errors.New("failed to release transaction promo code")
+			}
+		}
+	}
+
+	transaction, err = transactionS.transactionRepository.UpdateTransaction(ctx, tx, transaction)
+	if err != nil {
+		transactionS.transactionRepository.RollbackTx(ctx, tx)
+		return entity.Transaction{}, errors.New("failed to process transaction status change")
+	}
+
+	err = transactionS.transactionRepository.CommitTx(ctx, tx)
+	if err != nil {
+		return entity.Transaction{}, errors.New("failed to process transaction status change")
+	}
+
+	transactionS.seatEventService.Publish(released)
+	return transaction, nil
+}
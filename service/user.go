@@ -2,31 +2,49 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fp-rpl/common"
 	"fp-rpl/dto"
 	"fp-rpl/entity"
 	"fp-rpl/repository"
 	"fp-rpl/utils"
+	"reflect"
+	"time"
 
 	"github.com/jinzhu/copier"
 )
 
+// ErrUserPermissionDenied is returned when staff set a role or act on a
+// user without holding every permission it would hand out
+var ErrUserPermissionDenied = errors.New("you lack permissions held by the role of the user")
+
 type userService struct {
 	userRepository repository.UserRepository
+	roleRepository repository.RoleRepository
 }
 
 type UserService interface {
 	VerifyLogin(ctx context.Context, identifier string, password string) bool
 	CreateNewUser(ctx context.Context, userDTO dto.UserRegisterRequest) (entity.User, error)
-	GetAllUsers(ctx context.Context) ([]entity.User, error)
+	GetAllUsers(ctx context.Context, query common.Query) ([]entity.User, common.Meta, error)
 	GetUserByIdentifier(ctx context.Context, identifier string) (entity.User, error)
 	GetUserByUsernameOrEmail(ctx context.Context, username string, email string) (entity.User, error)
 	UpdateSelfName(ctx context.Context, userDTO dto.UserNameUpdateRequest, id uint64) (entity.User, error)
+	UpdateSelfBirthDate(ctx context.Context, userDTO dto.UserBirthDateUpdateRequest, id uint64) (entity.User, error)
+	UpdateSelfPassword(ctx context.Context, userDTO dto.UserPasswordUpdateRequest, id uint64) (entity.User, error)
 	GetUserByID(ctx context.Context, id uint64) (entity.User, error)
 	DeleteSelfUser(ctx context.Context, id uint64) error
+	CreateUser(ctx context.Context, userDTO dto.UserCreateRequest, editor AccessClaims) (entity.User, error)
+	UpdateUser(ctx context.Context, userDTO dto.UserUpdateRequest, user entity.User, editor AccessClaims) (entity.User, error)
+	SuspendUser(ctx context.Context, user entity.User, editor AccessClaims) (entity.User, error)
+	ReactivateUser(ctx context.Context, user entity.User, editor AccessClaims) (entity.User, error)
+	ResetUserPassword(ctx context.Context, password string, user entity.User, editor AccessClaims) (string, error)
 }
 
-func NewUserService(userR repository.UserRepository) UserService {
-	return &userService{userRepository: userR}
+func NewUserService(userR repository.UserRepository, roleR repository.RoleRepository) UserService {
+	return &userService{userRepository: userR, roleRepository: roleR}
 }
 
 func (userS *userService) VerifyLogin(ctx context.Context, identifier string, password string) bool {
@@ -45,6 +63,21 @@ func (userS *userService) VerifyLogin(ctx context.Context, identifier string, pa
 	return false
 }
 
+// ParseBirthDate reads a YYYY-MM-DD birth date, which must not be after the
+// day of now in the cinema timezone
+func ParseBirthDate(date string, now time.Time) (time.Time, error) {
+	birthDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, errors.New("birth date must use the YYYY-MM-DD format")
+	}
+
+	now = now.In(common.Location())
+	if birthDate.After(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		return time.Time{}, errors.New("birth date must not be in the future")
+	}
+	return birthDate, nil
+}
+
 func (userS *userService) CreateNewUser(ctx context.Context, userDTO dto.UserRegisterRequest) (entity.User, error) {
 	// Fill user role
 	userDTO.Role = "user"
@@ -53,6 +86,14 @@ func (userS *userService) CreateNewUser(ctx context.Context, userDTO dto.UserReg
 	var user entity.User
 	copier.Copy(&user, &userDTO)
 
+	if userDTO.BirthDate != "" {
+		birthDate, err := ParseBirthDate(userDTO.BirthDate, time.Now())
+		if err != nil {
+			return entity.User{}, err
+		}
+		user.BirthDate = &birthDate
+	}
+
 	// create new user
 	newUser, err := userS.userRepository.CreateNewUser(ctx, nil, user)
 	if err != nil {
@@ -61,12 +102,12 @@ func (userS *userService) CreateNewUser(ctx context.Context, userDTO dto.UserReg
 	return newUser, nil
 }
 
-func (userS *userService) GetAllUsers(ctx context.Context) ([]entity.User, error) {
-	users, err := userS.userRepository.GetAllUsers(ctx, nil)
+func (userS *userService) GetAllUsers(ctx context.Context, query common.Query) ([]entity.User, common.Meta, error) {
+	users, total, err := userS.userRepository.GetAllUsers(ctx, nil, query)
 	if err != nil {
-		return []entity.User{}, err
+		return []entity.User{}, common.Meta{}, err
 	}
-	return users, nil
+	return users, query.Meta(total), nil
 }
 
 func (userS *userService) GetUserByIdentifier(ctx context.Context, identifier string) (entity.User, error) {
@@ -106,6 +147,62 @@ func (userS *userService) UpdateSelfName(ctx context.Context, userDTO dto.UserNa
 	return user, nil
 }
 
+// UpdateSelfBirthDate sets the birth date of a user. It can only be set
+// once, so that users cannot change their age to book a rated film.
+func (userS *userService) UpdateSelfBirthDate(ctx context.Context, userDTO dto.UserBirthDateUpdateRequest, id uint64) (entity.User, error) {
+	birthDate, err := ParseBirthDate(userDTO.BirthDate, time.Now())
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user, err := userS.userRepository.GetUserByID(ctx, nil, id)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		return entity.User{}, nil
+	}
+
+	if user.BirthDate != nil {
+		return entity.User{}, errors.New("birth date has already been set")
+	}
+
+	user, err = userS.userRepository.UpdateBirthDateUser(ctx, nil, birthDate, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// UpdateSelfPassword changes the password of a user who knows the current
+// one. The other devices the user is logged in on should be logged out.
+func (userS *userService) UpdateSelfPassword(ctx context.Context, userDTO dto.UserPasswordUpdateRequest, id uint64) (entity.User, error) {
+	user, err := userS.userRepository.GetUserByID(ctx, nil, id)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if reflect.DeepEqual(user, entity.User{}) {
+		return entity.User{}, nil
+	}
+
+	passwordCheck, err := utils.PasswordCompare(user.Password, []byte(userDTO.OldPassword))
+	if err != nil || !passwordCheck {
+		return entity.User{}, errors.New("old password is incorrect")
+	}
+
+	if userDTO.NewPassword == userDTO.OldPassword {
+		return entity.User{}, errors.New("new password must differ from the old one")
+	}
+
+	user, err = userS.userRepository.UpdatePasswordUser(ctx, nil, userDTO.NewPassword, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
 func (userS *userService) DeleteSelfUser(ctx context.Context, id uint64) error {
 	err := userS.userRepository.DeleteUserByID(ctx, nil, id)
 	if err != nil {
@@ -113,3 +210,145 @@ func (userS *userService) DeleteSelfUser(ctx context.Context, id uint64) error {
 	}
 	return nil
 }
+
+// CreateUser creates an account of any role, unlike CreateNewUser which
+// registers customers. Accounts are customers unless a role is given.
+func (userS *userService) CreateUser(ctx context.Context, userDTO dto.UserCreateRequest, editor AccessClaims) (entity.User, error) {
+	if userDTO.Role == "" {
+		userDTO.Role = entity.RoleUser
+	} else {
+		err := checkRoleGrant(ctx, userS.roleRepository, userDTO.Role, editor)
+		if err != nil {
+			return entity.User{}, err
+		}
+	}
+
+	var user entity.User
+	copier.Copy(&user, &userDTO)
+
+	if userDTO.BirthDate != "" {
+		birthDate, err := ParseBirthDate(userDTO.BirthDate, time.Now())
+		if err != nil {
+			return entity.User{}, err
+		}
+		user.BirthDate = &birthDate
+	}
+
+	newUser, err := userS.userRepository.CreateNewUser(ctx, nil, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return newUser, nil
+}
+
+// UpdateUser changes the profile and role of a user. Unlike users
+// themselves, staff can correct a birth date that has already been set.
+// Nobody can change their own role.
+func (userS *userService) UpdateUser(ctx context.Context, userDTO dto.UserUpdateRequest, user entity.User, editor AccessClaims) (entity.User, error) {
+	err := checkRolePermissions(ctx, userS.roleRepository, user.Role, editor)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if userDTO.Role != "" && userDTO.Role != user.Role {
+		if user.ID == editor.UserID {
+			return entity.User{}, errors.New("you cannot change your own role")
+		}
+
+		err := checkRoleGrant(ctx, userS.roleRepository, userDTO.Role, editor)
+		if err != nil {
+			return entity.User{}, err
+		}
+		user.Role = userDTO.Role
+	}
+
+	if userDTO.BirthDate != "" {
+		birthDate, err := ParseBirthDate(userDTO.BirthDate, time.Now())
+		if err != nil {
+			return entity.User{}, err
+		}
+		user.BirthDate = &birthDate
+	}
+
+	if userDTO.Name != "" {
+		user.Name = userDTO.Name
+	}
+	if userDTO.Email != "" {
+		user.Email = userDTO.Email
+	}
+	if userDTO.NoTelp != "" {
+		user.NoTelp = userDTO.NoTelp
+	}
+
+	user, err = userS.userRepository.UpdateUser(ctx, nil, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// SuspendUser stops a user from logging in. The tokens the user already has
+// must be revoked as well.
+func (userS *userService) SuspendUser(ctx context.Context, user entity.User, editor AccessClaims) (entity.User, error) {
+	if user.ID == editor.UserID {
+		return entity.User{}, errors.New("you cannot suspend your own account")
+	}
+
+	err := checkRolePermissions(ctx, userS.roleRepository, user.Role, editor)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if user.IsSuspended() {
+		return entity.User{}, errors.New("user is already suspended")
+	}
+
+	now := time.Now()
+	user, err = userS.userRepository.UpdateSuspensionUser(ctx, nil, &now, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+func (userS *userService) ReactivateUser(ctx context.Context, user entity.User, editor AccessClaims) (entity.User, error) {
+	if !user.IsSuspended() {
+		return entity.User{}, errors.New("user is not suspended")
+	}
+
+	err := checkRolePermissions(ctx, userS.roleRepository, user.Role, editor)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user, err = userS.userRepository.UpdateSuspensionUser(ctx, nil, nil, user)
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// ResetUserPassword replaces the password of a user, making up a random one
+// when none is given, and returns the new password. The tokens the user
+// already has must be revoked as well.
+func (userS *userService) ResetUserPassword(ctx context.Context, password string, user entity.User, editor AccessClaims) (string, error) {
+	err := checkRolePermissions(ctx, userS.roleRepository, user.Role, editor)
+	if err != nil {
+		return "", err
+	}
+
+	if password == "" {
+		secret := make([]byte, 12)
+		_, err = rand.Read(secret)
+		if err != nil {
+			return "", err
+		}
+		password = base64.RawURLEncoding.EncodeToString(secret)
+	}
+
+	_, err = userS.userRepository.UpdatePasswordUser(ctx, nil, password, user)
+	if err != nil {
+		return "", err
+	}
+	return password, nil
+}
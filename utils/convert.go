@@ -1,5 +1,22 @@
 package utils
 
+import (
+	"errors"
+	"strconv"
+)
+
 func IntToChar(i int) rune {
 	return rune('A' - 1 + i)
-}
\ No newline at end of file
+}
+
+func ParseSpotName(name string) (string, int, error) {
+	if len(name) < 2 || name[0] < 'A' || name[0] > 'Z' {
+		return "", 0, errors.New("invalid spot name " + name)
+	}
+
+	number, err := strconv.Atoi(name[1:])
+	if err != nil || number <= 0 {
+		return "", 0, errors.New("invalid spot name " + name)
+	}
+	return string(name[0]), number, nil
+}
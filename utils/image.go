@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// FlattenImage copies an image into an opaque RGBA image, laying transparent
+// parts over white since the result is meant to be encoded as JPEG
+func FlattenImage(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+	return flat
+}
+
+// ResizeImage scales an image made by FlattenImage down to width, keeping
+// its aspect ratio, by averaging the pixels each new pixel covers. Images
+// narrower than width keep their size.
+func ResizeImage(flat *image.RGBA, width int) *image.RGBA {
+	bounds := flat.Bounds()
+	if width > bounds.Dx() {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * bounds.Dy() / height
+		y1 := (y + 1) * bounds.Dy() / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := x * bounds.Dx() / width
+			x1 := (x + 1) * bounds.Dx() / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, count uint64
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(row[sx*4])
+					g += uint64(row[sx*4+1])
+					b += uint64(row[sx*4+2])
+					count++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify turns a name into a lower case, dash separated slug, e.g.
+// "Science Fiction" into "science-fiction"
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteRune('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return slug.String()
+}